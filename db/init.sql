@@ -31,20 +31,562 @@ CREATE TABLE organization_responsible
     user_id         UUID REFERENCES employee (id) ON DELETE CASCADE
 );
 
-create type service_type as enum ('Construction', 'Delivery', 'Manufacture');
+create table service_type
+(
+    name       varchar(100) primary key,
+    parent     varchar(100) references service_type (name) on update cascade,
+    deprecated boolean   default false not null,
+    created_at timestamp default now() not null,
+    updated_at timestamp default now() not null,
+    check (parent <> name)
+);
+
+insert into service_type (name)
+values ('Construction'),
+       ('Delivery'),
+       ('Manufacture');
+
 create type tender_status as enum ('Created', 'Published', 'Closed');
+create type tender_visibility as enum ('Public', 'InviteOnly');
+
+-- Tender and bid versions and audit entries form SHA-256 hash chains: each
+-- row stores the hash of the previous link and sha256(prev_hash || content),
+-- where content is the canonical jsonb text of the row's own columns.
+create function chain_hash(prev_hash char(64), content text) returns char(64) as
+$$
+select encode(sha256(convert_to(coalesce(prev_hash, '') || content, 'UTF8')), 'hex');
+$$ language sql immutable;
+
+-- Chained rows are never changed in place: a change is a new version, so
+-- updates and deletes would only break the chain.
+create function chain_immutable() returns trigger as
+$$
+begin
+    raise exception '% is append-only', tg_table_name;
+end;
+$$ language plpgsql;
 
 create table tender
 (
     id              uuid      default uuid_generate_v4()                not null,
     name            varchar(100)                                        not null,
     description     varchar(500)                                        not null,
-    service_type    service_type                                        not null,
+    service_type    varchar(100) references service_type (name)
+                        on update cascade                               not null,
     status          tender_status                                       not null,
     organization_id uuid REFERENCES organization (id) on delete cascade not null,
     created_at      timestamp default now()                             not null,
     updated_at      timestamp default now()                             not null,
     creator_id      uuid references employee (id)                       not null,
     version         integer   default 1                                 not null,
-    primary key (id, version)
-);
\ No newline at end of file
+    deadline        timestamp,
+    publish_at      timestamp,
+    budget_amount   numeric(17, 2) check (budget_amount >= 0),
+    budget_currency char(3),
+    visibility      tender_visibility default 'Public'                not null,
+    prev_hash       char(64),
+    hash            char(64)                                            not null,
+    search_vector   tsvector generated always as (
+        setweight(to_tsvector('russian'::regconfig, name), 'A') ||
+        setweight(to_tsvector('english'::regconfig, name), 'A') ||
+        setweight(to_tsvector('russian'::regconfig, description), 'B') ||
+        setweight(to_tsvector('english'::regconfig, description), 'B')
+        ) stored,
+    primary key (id, version),
+    check ((budget_amount is null) = (budget_currency is null))
+);
+
+create index tender_search_vector_idx on tender using gin (search_vector);
+
+create index tender_name_idx on tender (name, id);
+create index tender_created_at_idx on tender (created_at, id);
+create index tender_updated_at_idx on tender (updated_at, id);
+create index tender_organization_id_idx on tender (organization_id);
+create index tender_creator_id_idx on tender (creator_id);
+create index tender_deadline_idx on tender (deadline) where status = 'Published' and deadline is not null;
+create index tender_budget_idx on tender (budget_currency, budget_amount);
+create index tender_publish_at_idx on tender (publish_at) where status = 'Created' and publish_at is not null;
+
+-- service_type is left out of the chained content: it references a service type
+-- by its name, and renaming the service type cascades to every tender version.
+create function tender_chain_content(t tender) returns text as
+$$
+select jsonb_build_object('id', t.id, 'name', t.name, 'description', t.description,
+                          'status', t.status, 'organization_id', t.organization_id, 'created_at', t.created_at,
+                          'updated_at', t.updated_at, 'creator_id', t.creator_id, 'version', t.version,
+                          'deadline', t.deadline, 'publish_at', t.publish_at, 'budget_amount', t.budget_amount,
+                          'budget_currency', t.budget_currency, 'visibility', t.visibility)::text;
+$$ language sql stable;
+
+create function tender_chain() returns trigger as
+$$
+begin
+    new.prev_hash := (select hash from tender where id = new.id and version < new.version order by version desc limit 1);
+    new.hash := chain_hash(new.prev_hash, tender_chain_content(new));
+    return new;
+end;
+$$ language plpgsql;
+
+create trigger tender_chain
+    before insert
+    on tender
+    for each row
+execute function tender_chain();
+
+-- Only the cascade of a service type rename may update a tender version, and it
+-- leaves the chained content as it was.
+create function tender_immutable() returns trigger as
+$$
+begin
+    if tg_op = 'UPDATE' and tender_chain_content(new) = tender_chain_content(old)
+        and new.prev_hash is not distinct from old.prev_hash and new.hash = old.hash then
+        return new;
+    end if;
+    raise exception '% is append-only', tg_table_name;
+end;
+$$ language plpgsql;
+
+create trigger tender_immutable
+    before update or delete
+    on tender
+    for each row
+execute function tender_immutable();
+
+create trigger tender_no_truncate
+    before truncate
+    on tender
+    for each statement
+execute function chain_immutable();
+
+create table tender_current
+(
+    id      uuid primary key,
+    version integer not null,
+    foreign key (id, version) references tender (id, version) on delete cascade
+);
+
+create function tender_set_current() returns trigger as
+$$
+begin
+    insert into tender_current (id, version)
+    values (new.id, new.version)
+    on conflict (id) do update set version = excluded.version
+    where tender_current.version < excluded.version;
+    return new;
+end;
+$$ language plpgsql;
+
+create trigger tender_set_current
+    after insert
+    on tender
+    for each row
+execute function tender_set_current();
+
+create table tender_invitation
+(
+    tender_id       uuid references tender_current (id) on delete cascade not null,
+    organization_id uuid references organization (id) on delete cascade   not null,
+    invited_by      uuid references employee (id)                         not null,
+    created_at      timestamp default now()                               not null,
+    primary key (tender_id, organization_id)
+);
+
+create index tender_invitation_organization_id_idx on tender_invitation (organization_id);
+
+create table tender_template
+(
+    id                 uuid      default uuid_generate_v4()                  not null primary key,
+    organization_id    uuid references organization (id) on delete cascade   not null,
+    name               varchar(100)                                          not null,
+    tender_name        varchar(500)                                          not null,
+    tender_description varchar(2000)                                         not null,
+    service_type       varchar(100) references service_type (name)
+                           on update cascade                                 not null,
+    visibility         tender_visibility default 'Public'                    not null,
+    budget_amount      numeric(17, 2) check (budget_amount >= 0),
+    budget_currency    char(3),
+    creator_id         uuid references employee (id)                         not null,
+    created_at         timestamp default now()                               not null,
+    updated_at         timestamp default now()                               not null,
+    unique (organization_id, name),
+    check ((budget_amount is null) = (budget_currency is null))
+);
+
+create type lot_status as enum ('Open', 'Awarded', 'Cancelled');
+
+create table tender_lot
+(
+    id              uuid      default uuid_generate_v4()                  not null primary key,
+    tender_id       uuid references tender_current (id) on delete cascade not null,
+    name            varchar(100)                                          not null,
+    description     varchar(500)                                          not null,
+    budget_amount   numeric(17, 2) check (budget_amount >= 0),
+    budget_currency char(3),
+    quantity        integer check (quantity > 0)                          not null,
+    status          lot_status default 'Open'                             not null,
+    awarded_bid_id  uuid,
+    created_at      timestamp default now()                               not null,
+    updated_at      timestamp default now()                               not null,
+    check ((budget_amount is null) = (budget_currency is null)),
+    check ((status = 'Awarded') = (awarded_bid_id is not null))
+);
+
+create index tender_lot_tender_id_idx on tender_lot (tender_id, status);
+
+create type bid_status as enum ('Created', 'Published', 'Canceled');
+create type bid_author_type as enum ('Organization', 'User');
+
+create table bid
+(
+    id             uuid      default uuid_generate_v4()                  not null,
+    name           varchar(100)                                          not null,
+    description    varchar(500)                                          not null,
+    status         bid_status                                            not null,
+    tender_id      uuid references tender_current (id) on delete cascade not null,
+    author_type    bid_author_type                                       not null,
+    author_id      uuid                                                  not null,
+    version        integer   default 1                                   not null,
+    created_at     timestamp default now()                               not null,
+    updated_at     timestamp default now()                               not null,
+    price_amount   numeric(17, 2) check (price_amount >= 0),
+    price_currency char(3),
+    delivery_days  integer check (delivery_days >= 0),
+    terms          varchar(2000),
+    lot_id         uuid references tender_lot (id),
+    prev_hash      char(64),
+    hash           char(64)                                              not null,
+    primary key (id, version),
+    check ((price_amount is null) = (price_currency is null))
+);
+
+create function bid_chain_content(b bid) returns text as
+$$
+select jsonb_build_object('id', b.id, 'name', b.name, 'description', b.description, 'status', b.status,
+                          'tender_id', b.tender_id, 'author_type', b.author_type, 'author_id', b.author_id,
+                          'version', b.version, 'created_at', b.created_at, 'updated_at', b.updated_at,
+                          'price_amount', b.price_amount, 'price_currency', b.price_currency,
+                          'delivery_days', b.delivery_days, 'terms', b.terms, 'lot_id', b.lot_id)::text;
+$$ language sql stable;
+
+create function bid_chain() returns trigger as
+$$
+begin
+    new.prev_hash := (select hash from bid where id = new.id and version < new.version order by version desc limit 1);
+    new.hash := chain_hash(new.prev_hash, bid_chain_content(new));
+    return new;
+end;
+$$ language plpgsql;
+
+create trigger bid_chain
+    before insert
+    on bid
+    for each row
+execute function bid_chain();
+
+create trigger bid_immutable
+    before update or delete
+    on bid
+    for each row
+execute function chain_immutable();
+
+create trigger bid_no_truncate
+    before truncate
+    on bid
+    for each statement
+execute function chain_immutable();
+
+create index bid_tender_id_idx on bid (tender_id, status);
+
+create type bid_decision_value as enum ('Approved', 'Rejected');
+
+-- Decisions of tender responsibles on a bid. One rejection rejects the bid;
+-- it is approved once min(3, number of responsibles) approvals are given.
+create table bid_decision
+(
+    bid_id      uuid                              not null,
+    employee_id uuid references employee (id)     not null,
+    decision    bid_decision_value                not null,
+    created_at  timestamp default now()           not null,
+    primary key (bid_id, employee_id)
+);
+
+create table attachment
+(
+    id             uuid primary key,
+    tender_id      uuid                          not null,
+    tender_version integer                       not null,
+    bid_id         uuid,
+    bid_version    integer,
+    file_name      varchar(255)                  not null,
+    content_type   varchar(100)                  not null,
+    size           bigint                        not null check (size >= 0),
+    sha256         char(64)                      not null,
+    storage_key    varchar(100)                  not null,
+    uploader_id    uuid references employee (id) not null,
+    created_at     timestamp default now()       not null,
+    foreign key (tender_id, tender_version) references tender (id, version) on delete cascade,
+    foreign key (bid_id, bid_version) references bid (id, version) on delete cascade
+);
+
+create index attachment_tender_idx on attachment (tender_id, tender_version);
+create index attachment_bid_idx on attachment (bid_id, bid_version);
+
+create table tender_question
+(
+    id            uuid      default uuid_generate_v4()                  primary key,
+    tender_id     uuid references tender_current (id) on delete cascade not null,
+    author_id     uuid references employee (id) on delete cascade       not null,
+    text          varchar(1000)                                         not null,
+    created_at    timestamp default now()                               not null,
+    answer        varchar(2000),
+    answered_by   uuid references employee (id) on delete set null,
+    answered_at   timestamp,
+    answer_public boolean   default false                               not null
+);
+
+create index tender_question_tender_idx on tender_question (tender_id, created_at);
+create index tender_question_author_idx on tender_question (author_id, created_at);
+
+create table audit_log
+(
+    id              bigserial primary key,
+    occurred_at     timestamp default now() not null,
+    actor_id        uuid references employee (id),
+    actor_username  varchar(50),
+    action          varchar(100)            not null,
+    entity_type     varchar(50)             not null,
+    entity_id       varchar(100)            not null,
+    entity_version  integer,
+    organization_id uuid,
+    request_id      varchar(100),
+    client_ip       varchar(45),
+    before          jsonb,
+    after           jsonb,
+    prev_hash       char(64),
+    hash            char(64)                not null
+);
+
+create index audit_log_organization_id_idx on audit_log (organization_id, id);
+create index audit_log_entity_idx on audit_log (entity_type, entity_id, id);
+
+create function audit_log_chain_content(a audit_log) returns text as
+$$
+select jsonb_build_object('id', a.id, 'occurred_at', a.occurred_at, 'actor_id', a.actor_id,
+                          'actor_username', a.actor_username, 'action', a.action, 'entity_type', a.entity_type,
+                          'entity_id', a.entity_id, 'entity_version', a.entity_version,
+                          'organization_id', a.organization_id, 'request_id', a.request_id, 'client_ip', a.client_ip,
+                          'before', a.before, 'after', a.after)::text;
+$$ language sql stable;
+
+-- Audit entries form a single chain. The advisory lock is held until commit and
+-- the id is taken under it, so ids follow the order in which links are made.
+create function audit_log_chain() returns trigger as
+$$
+begin
+    perform pg_advisory_xact_lock(hashtext('audit_log_chain'));
+    new.id := nextval(pg_get_serial_sequence('audit_log', 'id'));
+    new.prev_hash := (select hash from audit_log order by id desc limit 1);
+    new.hash := chain_hash(new.prev_hash, audit_log_chain_content(new));
+    return new;
+end;
+$$ language plpgsql;
+
+create trigger audit_log_chain
+    before insert
+    on audit_log
+    for each row
+execute function audit_log_chain();
+
+create function audit_log_immutable() returns trigger as
+$$
+begin
+    raise exception 'audit_log is append-only';
+end;
+$$ language plpgsql;
+
+create trigger audit_log_immutable
+    before update or delete
+    on audit_log
+    for each row
+execute function audit_log_immutable();
+
+create trigger audit_log_no_truncate
+    before truncate
+    on audit_log
+    for each statement
+execute function audit_log_immutable();
+
+create type outbox_status as enum ('Pending', 'Delivered', 'DeadLetter');
+
+create table outbox_event
+(
+    id                bigserial primary key,
+    event_type        varchar(100)                    not null,
+    aggregate_type    varchar(50)                     not null,
+    aggregate_id      uuid                            not null,
+    aggregate_version integer                         not null,
+    payload           jsonb                           not null,
+    created_at        timestamp     default now()     not null,
+    status            outbox_status default 'Pending' not null,
+    delivered_at      timestamp
+);
+
+create index outbox_event_pending_idx on outbox_event (id) where status = 'Pending';
+
+-- Delivery state of an event for each sink. Sinks go through the outbox
+-- independently, so a failing sink only holds back its own deliveries. A row
+-- appears on the first attempt; the event leaves Pending once every sink has
+-- delivered it (Delivered) or given up on it (DeadLetter).
+create table outbox_delivery
+(
+    event_id        bigint references outbox_event (id) not null,
+    sink            varchar(50)                          not null,
+    status          outbox_status default 'Pending'      not null,
+    attempts        integer       default 0              not null,
+    next_attempt_at timestamp     default now()          not null,
+    last_error      text,
+    delivered_at    timestamp,
+    primary key (event_id, sink)
+);
+
+-- Readers (the dispatcher and the event stream) page through events by id, so
+-- ids must follow commit order. As for the audit chain, the id is taken under
+-- an advisory lock held until commit: a transaction cannot get an id until
+-- every transaction that got a smaller one has committed or rolled back.
+create function outbox_event_order() returns trigger as
+$$
+begin
+    perform pg_advisory_xact_lock(hashtext('outbox_event_order'));
+    new.id := nextval(pg_get_serial_sequence('outbox_event', 'id'));
+    return new;
+end;
+$$ language plpgsql;
+
+create trigger outbox_event_order
+    before insert
+    on outbox_event
+    for each row
+execute function outbox_event_order();
+
+create table webhook
+(
+    id              uuid      default uuid_generate_v4()                  not null primary key,
+    organization_id uuid references organization (id) on delete cascade   not null,
+    url             varchar(2000)                                         not null,
+    event_types     varchar(100)[]                                        not null,
+    secret          varchar(200)                                          not null,
+    active          boolean   default true                                not null,
+    creator_id      uuid references employee (id)                         not null,
+    created_at      timestamp default now()                               not null,
+    updated_at      timestamp default now()                               not null
+);
+
+create index webhook_organization_idx on webhook (organization_id);
+
+create type webhook_delivery_status as enum ('Pending', 'Delivered', 'Failed');
+
+create table webhook_delivery
+(
+    id               uuid                    default uuid_generate_v4() not null primary key,
+    webhook_id       uuid references webhook (id) on delete cascade     not null,
+    event_id         bigint references outbox_event (id)                not null,
+    event_type       varchar(100)                                       not null,
+    payload          jsonb                                              not null,
+    redelivery_of    uuid references webhook_delivery (id),
+    status           webhook_delivery_status default 'Pending'          not null,
+    attempts         integer                 default 0                  not null,
+    next_attempt_at  timestamp               default now()              not null,
+    last_status_code integer,
+    last_error       text,
+    created_at       timestamp               default now()              not null,
+    delivered_at     timestamp
+);
+
+create unique index webhook_delivery_event_idx on webhook_delivery (webhook_id, event_id) where redelivery_of is null;
+create index webhook_delivery_webhook_idx on webhook_delivery (webhook_id, created_at desc);
+create index webhook_delivery_pending_idx on webhook_delivery (next_attempt_at) where status = 'Pending';
+
+create function outbox_event_notify() returns trigger as
+$$
+begin
+    perform pg_notify('outbox_event', new.id::text);
+    return null;
+end;
+$$ language plpgsql;
+
+create trigger outbox_event_notify
+    after insert
+    on outbox_event
+    for each row
+execute function outbox_event_notify();
+
+create table saved_search
+(
+    id              uuid      default uuid_generate_v4()               not null primary key,
+    employee_id     uuid references employee (id) on delete cascade    not null,
+    name            varchar(100)                                       not null,
+    service_types   varchar(100)[] default '{}'                        not null,
+    keywords        varchar(200),
+    budget_min      numeric(17, 2) check (budget_min >= 0),
+    budget_max      numeric(17, 2) check (budget_max >= 0),
+    budget_currency char(3),
+    created_at      timestamp default now()                            not null,
+    unique (employee_id, name),
+    check (budget_min is null or budget_max is null or budget_min <= budget_max)
+);
+
+create table notification
+(
+    id              uuid      default uuid_generate_v4()                  not null primary key,
+    employee_id     uuid references employee (id) on delete cascade       not null,
+    type            varchar(50)                                           not null,
+    tender_id       uuid                                                  not null,
+    tender_version  integer                                               not null,
+    saved_search_id uuid references saved_search (id) on delete set null,
+    title           varchar(500)                                          not null,
+    created_at      timestamp default now()                               not null,
+    read_at         timestamp,
+    unique (employee_id, type, tender_id)
+);
+
+create index notification_employee_idx on notification (employee_id, created_at desc);
+create index notification_unread_idx on notification (employee_id) where read_at is null;
+
+create table email_preference
+(
+    employee_id   uuid references employee (id) on delete cascade not null primary key,
+    email         varchar(254),
+    bid_submitted boolean   default true                          not null,
+    bid_decided   boolean   default true                          not null,
+    updated_at    timestamp default now()                         not null
+);
+
+create type email_status as enum ('Pending', 'Sent', 'Failed');
+
+create table email_message
+(
+    id              bigserial primary key,
+    event_id        bigint references outbox_event (id)            not null,
+    recipient_id    uuid references employee (id) on delete cascade not null,
+    email           varchar(254)                                   not null,
+    subject         varchar(500)                                   not null,
+    body            text                                           not null,
+    status          email_status default 'Pending'                 not null,
+    attempts        integer      default 0                         not null,
+    next_attempt_at timestamp    default now()                     not null,
+    last_error      text,
+    created_at      timestamp    default now()                     not null,
+    sent_at         timestamp,
+    unique (event_id, recipient_id)
+);
+
+create index email_message_pending_idx on email_message (next_attempt_at) where status = 'Pending';
+
+-- Bump together with database.SchemaVersion on every schema change.
+create table schema_version
+(
+    version    integer primary key,
+    applied_at timestamp default now() not null
+);
+
+insert into schema_version (version) values (1), (2), (3), (4), (5), (6);
@@ -0,0 +1,95 @@
+package chain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"zadanie-6105/database"
+	"zadanie-6105/model"
+)
+
+// Chains lists the hash chains in the order they are verified.
+var Chains = []string{database.ChainTenders, database.ChainBids, database.ChainAudit}
+
+var errStop = errors.New("stop")
+
+type Report struct {
+	Chain   string
+	Checked int
+	Broken  *BrokenLink
+}
+
+// BrokenLink is the first link of a chain whose stored hashes do not match the
+// recomputed ones.
+type BrokenLink struct {
+	ID     string
+	Seq    int64
+	Reason string
+}
+
+func (r *Report) Valid() bool {
+	return r.Broken == nil
+}
+
+// Hash returns the link hash for content following prevHash.
+func Hash(prevHash *string, content string) string {
+	h := sha256.New()
+	if prevHash != nil {
+		h.Write([]byte(*prevHash))
+	}
+	h.Write([]byte(content))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Verify recomputes a chain from the stored row contents and reports the first
+// broken link, if any.
+func Verify(ctx context.Context, db database.DbConnector, chain string) (*Report, error) {
+	report := &Report{Chain: chain}
+	var prev *model.ChainLink
+	err := db.ScanChain(ctx, chain, func(link *model.ChainLink) error {
+		var expectedPrev *string
+		if prev != nil && prev.Group == link.Group {
+			expectedPrev = &prev.Hash
+		}
+		switch {
+		case !equalHashes(link.PrevHash, expectedPrev):
+			report.Broken = &BrokenLink{ID: link.ID, Seq: link.Seq, Reason: "previous hash does not match the previous link"}
+		case Hash(link.PrevHash, link.Content) != link.Hash:
+			report.Broken = &BrokenLink{ID: link.ID, Seq: link.Seq, Reason: "hash does not match the row content"}
+		}
+		if report.Broken != nil {
+			return errStop
+		}
+		report.Checked++
+		prev = link
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStop) {
+		return nil, err
+	}
+	return report, nil
+}
+
+// VerifyAll verifies every chain in Chains. Tender and bid versions are chained
+// per entity, so removing the newest versions of an entity leaves a valid,
+// shorter chain; only the audit log chain, which covers every change, would
+// still show the entries for the removed versions.
+func VerifyAll(ctx context.Context, db database.DbConnector) ([]*Report, error) {
+	reports := make([]*Report, 0, len(Chains))
+	for _, chain := range Chains {
+		report, err := Verify(ctx, db, chain)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+func equalHashes(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInitializeConfigRejectsNonPositiveIntervals(t *testing.T) {
+	for _, name := range []string{"SCHEDULER_INTERVAL", "OUTBOX_DISPATCH_INTERVAL", "WEBHOOK_DELIVERY_INTERVAL", "STREAM_POLL_INTERVAL", "STREAM_HEARTBEAT_INTERVAL", "MAIL_SEND_INTERVAL"} {
+		for _, value := range []string{"0s", "-1m"} {
+			t.Run(name+"="+value, func(t *testing.T) {
+				t.Setenv(name, value)
+				_, err := InitializeConfig()
+				if err == nil || !strings.Contains(err.Error(), name) {
+					t.Errorf("InitializeConfig() error = %v, want one naming %s", err, name)
+				}
+			})
+		}
+	}
+}
+
+func TestInitializeConfigDefaults(t *testing.T) {
+	cfg, err := InitializeConfig()
+	if err != nil {
+		t.Fatalf("InitializeConfig() error = %v", err)
+	}
+	if cfg.SchedulerInterval <= 0 {
+		t.Errorf("default scheduler interval is not positive: %s", cfg.SchedulerInterval)
+	}
+}
@@ -2,25 +2,118 @@ package database
 
 import (
 	"context"
+	"time"
 	"zadanie-6105/model"
 )
 
 const (
 	maxConns = 10
+	// SchemaVersion is the version of db/init.sql this build expects.
+	SchemaVersion = 6
 )
 
+// PoolStats is a snapshot of the connection pool. Counters and durations are
+// totals since the pool was created.
+type PoolStats struct {
+	AcquiredConns        int32
+	IdleConns            int32
+	TotalConns           int32
+	MaxConns             int32
+	AcquireCount         int64
+	EmptyAcquireCount    int64
+	CanceledAcquireCount int64
+	AcquireDuration      time.Duration
+}
+
 type DbConnector interface {
 	GetEmployeeByUsername(ctx context.Context, username string) (*model.Employee, error)
+	GetEmployeeByID(ctx context.Context, id string) (*model.Employee, error)
 	GetOrganizationById(ctx context.Context, id string) (*model.Organization, error)
 	IsEmployeeInOrganization(ctx context.Context, username, organizationID string) (bool, error)
 	IsEmployeeExists(ctx context.Context, username string) (bool, error)
 	IsTenderExists(ctx context.Context, id string) (bool, error)
 	GetMaxTenderVersion(ctx context.Context, id string) (int, error)
-	GetTenders(ctx context.Context, limit, offset int, serviceType []string) ([]model.Tender, error)
+	GetTenders(ctx context.Context, filter *TenderFilter) ([]model.Tender, error)
 	GetTenderByID(ctx context.Context, id string) (*model.Tender, error)
-	GetTendersByCreatorID(ctx context.Context, limit, offset int, creatorID string) ([]model.Tender, error)
-	SaveTender(ctx context.Context, t *model.Tender) (*model.Tender, error)
-	UpdateTender(ctx context.Context, t *model.Tender) (*model.Tender, error)
+	SaveTender(ctx context.Context, t *model.Tender, events ...model.EventType) (*model.Tender, error)
+	CloneTender(ctx context.Context, sourceID string, sourceVersion int, clone *model.Tender, events ...model.EventType) (*model.Tender, error)
+	UpdateTender(ctx context.Context, t *model.Tender, events ...model.EventType) (*model.Tender, error)
 	GetTenderByIdAndVersion(ctx context.Context, id string, version int) (*model.Tender, error)
-	RollbackTender(ctx context.Context, id string, version int) (*model.Tender, error)
+	RollbackTender(ctx context.Context, id string, version int, events ...model.EventType) (*model.Tender, error)
+	CloseExpiredTenders(ctx context.Context, now time.Time) ([]model.Tender, error)
+	PublishScheduledTenders(ctx context.Context, now time.Time, limit int) ([]model.Tender, error)
+	GetPublishedBidsByTenderID(ctx context.Context, tenderID string) ([]model.Bid, error)
+	GetServiceTypes(ctx context.Context) ([]model.ServiceType, error)
+	SaveServiceType(ctx context.Context, st *model.ServiceType) (*model.ServiceType, error)
+	UpdateServiceType(ctx context.Context, name string, st *model.ServiceType) (*model.ServiceType, error)
+	GetBidByID(ctx context.Context, id string) (*model.Bid, error)
+	SaveBid(ctx context.Context, bid *model.Bid) (*model.Bid, error)
+	UpdateBid(ctx context.Context, bid *model.Bid, events ...model.EventType) (*model.Bid, error)
+	SaveBidDecision(ctx context.Context, bid *model.Bid, employeeID string, decision model.BidDecision) (model.BidDecision, error)
+	SaveAttachment(ctx context.Context, a *model.Attachment) (*model.Attachment, error)
+	GetAttachmentByID(ctx context.Context, id string) (*model.Attachment, error)
+	GetTenderAttachments(ctx context.Context, tenderID string, version int) ([]model.Attachment, error)
+	GetBidAttachments(ctx context.Context, bidID string, version int) ([]model.Attachment, error)
+	SaveQuestion(ctx context.Context, q *model.Question) (*model.Question, error)
+	GetQuestionByID(ctx context.Context, id string) (*model.Question, error)
+	AnswerQuestion(ctx context.Context, q *model.Question) (*model.Question, error)
+	GetTenderQuestions(ctx context.Context, limit, offset int, tenderID, viewerID string, includePrivate bool) ([]model.Question, error)
+	GetQuestionsByAuthorID(ctx context.Context, limit, offset int, authorID string) ([]model.Question, error)
+	SaveTenderInvitation(ctx context.Context, inv *model.TenderInvitation) (*model.TenderInvitation, error)
+	DeleteTenderInvitation(ctx context.Context, tenderID, organizationID string) error
+	GetTenderInvitations(ctx context.Context, tenderID string) ([]model.TenderInvitation, error)
+	IsTenderInvitee(ctx context.Context, tenderID, organizationID, employeeID string) (bool, error)
+	SaveTemplate(ctx context.Context, tpl *model.TenderTemplate) (*model.TenderTemplate, error)
+	GetTemplateByID(ctx context.Context, id string) (*model.TenderTemplate, error)
+	GetOrganizationTemplates(ctx context.Context, limit, offset int, organizationID string) ([]model.TenderTemplate, error)
+	UpdateTemplate(ctx context.Context, tpl *model.TenderTemplate) (*model.TenderTemplate, error)
+	DeleteTemplate(ctx context.Context, id string) error
+	SaveAuditEntry(ctx context.Context, e *model.AuditEntry) (*model.AuditEntry, error)
+	InTx(ctx context.Context, fn func(db DbConnector) error) error
+	GetAuditEntries(ctx context.Context, filter *AuditFilter) ([]model.AuditEntry, error)
+	ScanChain(ctx context.Context, chain string, fn func(link *model.ChainLink) error) error
+	SaveLot(ctx context.Context, lot *model.Lot) (*model.Lot, error)
+	GetLotByID(ctx context.Context, id string) (*model.Lot, error)
+	GetTenderLots(ctx context.Context, tenderID string) ([]model.Lot, error)
+	UpdateLot(ctx context.Context, lot *model.Lot) (*model.Lot, error)
+	CloseLot(ctx context.Context, id string, status model.LotStatus, awardedBidID *string) (*model.Lot, *model.Tender, error)
+	CloseTenderIfLotsClosed(ctx context.Context, id string) (*model.Tender, error)
+	GetPendingOutboxDeliveries(ctx context.Context, sink string, limit int) ([]model.OutboxDelivery, error)
+	GetOutboxEvents(ctx context.Context, afterID, upToID int64, limit int) ([]model.OutboxEvent, error)
+	GetLastOutboxEventID(ctx context.Context) (int64, error)
+	ListenOutboxEvents(ctx context.Context, notify func()) error
+	MarkOutboxDelivered(ctx context.Context, eventID int64, sink string, sinks []string) error
+	RetryOutboxDelivery(ctx context.Context, eventID int64, sink string, nextAttemptAt time.Time, lastError string) error
+	DeadLetterOutboxDelivery(ctx context.Context, eventID int64, sink, lastError string, sinks []string) error
+	RunExclusive(ctx context.Context, name string, fn func(ctx context.Context) error) (bool, error)
+	SaveWebhook(ctx context.Context, hook *model.Webhook) (*model.Webhook, error)
+	GetWebhookByID(ctx context.Context, id string) (*model.Webhook, error)
+	GetOrganizationWebhooks(ctx context.Context, limit, offset int, organizationID string) ([]model.Webhook, error)
+	UpdateWebhook(ctx context.Context, hook *model.Webhook) (*model.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	SaveWebhookDeliveries(ctx context.Context, eventID int64) (int, error)
+	GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]model.WebhookDelivery, error)
+	GetWebhookDeliveries(ctx context.Context, limit, offset int, webhookID string) ([]model.WebhookDelivery, error)
+	GetWebhookDeliveryByID(ctx context.Context, id string) (*model.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, d *model.WebhookDelivery) error
+	RedeliverWebhookDelivery(ctx context.Context, id string) (*model.WebhookDelivery, error)
+	SaveSavedSearch(ctx context.Context, search *model.SavedSearch, maxPerEmployee int) (*model.SavedSearch, error)
+	GetSavedSearchByID(ctx context.Context, id string) (*model.SavedSearch, error)
+	GetEmployeeSavedSearches(ctx context.Context, limit, offset int, employeeID string) ([]model.SavedSearch, error)
+	DeleteSavedSearch(ctx context.Context, id string) error
+	SaveTenderMatchNotifications(ctx context.Context, tenderID string, version int) (int, error)
+	GetNotifications(ctx context.Context, limit, offset int, employeeID string, unreadOnly bool) ([]model.Notification, error)
+	CountUnreadNotifications(ctx context.Context, employeeID string) (int, error)
+	MarkNotificationRead(ctx context.Context, id, employeeID string) (*model.Notification, error)
+	MarkAllNotificationsRead(ctx context.Context, employeeID string) (int, error)
+	GetEmailPreferences(ctx context.Context, employeeID string) (*model.EmailPreferences, error)
+	SaveEmailPreferences(ctx context.Context, prefs *model.EmailPreferences) (*model.EmailPreferences, error)
+	GetEmailRecipients(ctx context.Context, eventType model.EventType, employeeID, organizationID string) ([]model.EmailRecipient, error)
+	SaveEmailMessages(ctx context.Context, messages []model.EmailMessage) (int, error)
+	GetDueEmailMessages(ctx context.Context, now time.Time, limit int) ([]model.EmailMessage, error)
+	UpdateEmailMessage(ctx context.Context, m *model.EmailMessage) error
+	Ping(ctx context.Context) error
+	PoolStats() PoolStats
+	GetSchemaVersion(ctx context.Context) (int, error)
+	Close(ctx context.Context) error
 }
@@ -0,0 +1,216 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"zadanie-6105/model"
+)
+
+type TenderSortField string
+
+const (
+	SortByName      TenderSortField = "name"
+	SortByCreatedAt TenderSortField = "createdAt"
+	SortByUpdatedAt TenderSortField = "updatedAt"
+)
+
+var tenderSortColumns = map[TenderSortField]struct {
+	column string
+	cast   string
+}{
+	SortByName:      {column: "t.name", cast: "varchar"},
+	SortByCreatedAt: {column: "t.created_at", cast: "timestamp"},
+	SortByUpdatedAt: {column: "t.updated_at", cast: "timestamp"},
+}
+
+func IsValidTenderSortField(field string) bool {
+	_, ok := tenderSortColumns[TenderSortField(field)]
+	return ok
+}
+
+func (f TenderSortField) Key(t *model.Tender) string {
+	switch f {
+	case SortByCreatedAt:
+		return t.CreatedAt.Format(time.RFC3339Nano)
+	case SortByUpdatedAt:
+		return t.UpdatedAt.Format(time.RFC3339Nano)
+	default:
+		return t.Name
+	}
+}
+
+type TenderCursor struct {
+	SortBy     TenderSortField `json:"s"`
+	Descending bool            `json:"d,omitempty"`
+	Key        string          `json:"k"`
+	ID         string          `json:"i"`
+}
+
+type TenderFilter struct {
+	Limit           int
+	Offset          int
+	After           *TenderCursor
+	ViewerID        string
+	ManagedBy       string
+	Scheduled       bool
+	Search          string
+	ServiceTypes    []string
+	OrganizationIDs []string
+	Statuses        []model.TenderStatus
+	CreatorID       string
+	CreatedFrom     *time.Time
+	CreatedTo       *time.Time
+	UpdatedFrom     *time.Time
+	UpdatedTo       *time.Time
+	BudgetMin       string
+	BudgetMax       string
+	BudgetCurrency  string
+	SortBy          TenderSortField
+	Descending      bool
+}
+
+func (f *TenderFilter) IsRankedBySearch() bool {
+	return f.Search != "" && f.SortBy == ""
+}
+
+func (f *TenderFilter) SortField() TenderSortField {
+	if f.SortBy == "" {
+		return SortByName
+	}
+	return f.SortBy
+}
+
+type queryArgs []any
+
+func (a *queryArgs) add(value any) string {
+	*a = append(*a, value)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+func (f *TenderFilter) buildQuery() (string, []any) {
+	var args queryArgs
+	from := "tender_current AS cur JOIN tender AS t ON t.id = cur.id AND t.version = cur.version"
+	var conditions []string
+	if f.ManagedBy != "" {
+		conditions = append(conditions, fmt.Sprintf(
+			"t.organization_id IN (SELECT organization_id FROM organization_responsible WHERE user_id = %s)", args.add(f.ManagedBy)))
+	} else {
+		viewer := args.add(nullableID(f.ViewerID))
+		conditions = append(conditions, fmt.Sprintf(`(t.creator_id = %[1]s OR t.status = 'Published' AND (t.visibility = 'Public'
+			OR EXISTS (SELECT 1 FROM organization_responsible AS r WHERE r.user_id = %[1]s AND (r.organization_id = t.organization_id
+				OR r.organization_id IN (SELECT organization_id FROM tender_invitation WHERE tender_id = t.id)))))`, viewer))
+	}
+	if f.Scheduled {
+		conditions = append(conditions, "t.publish_at IS NOT NULL")
+	}
+	if f.Search != "" {
+		p := args.add(f.Search)
+		from += fmt.Sprintf(", websearch_to_tsquery('russian', %s) || websearch_to_tsquery('english', %s) AS search_query", p, p)
+		conditions = append(conditions, "t.search_vector @@ search_query")
+	}
+	if len(f.ServiceTypes) > 0 {
+		conditions = append(conditions, fmt.Sprintf("t.service_type = ANY(%s)", args.add(f.ServiceTypes)))
+	}
+	if len(f.OrganizationIDs) > 0 {
+		conditions = append(conditions, fmt.Sprintf("t.organization_id = ANY(%s)", args.add(f.OrganizationIDs)))
+	}
+	if len(f.Statuses) > 0 {
+		statuses := make([]string, 0, len(f.Statuses))
+		for _, status := range f.Statuses {
+			statuses = append(statuses, string(status))
+		}
+		conditions = append(conditions, fmt.Sprintf("t.status = ANY(%s)", args.add(statuses)))
+	}
+	if f.CreatorID != "" {
+		conditions = append(conditions, fmt.Sprintf("t.creator_id = %s", args.add(f.CreatorID)))
+	}
+	if f.CreatedFrom != nil {
+		conditions = append(conditions, fmt.Sprintf("t.created_at >= %s", args.add(*f.CreatedFrom)))
+	}
+	if f.CreatedTo != nil {
+		conditions = append(conditions, fmt.Sprintf("t.created_at <= %s", args.add(*f.CreatedTo)))
+	}
+	if f.UpdatedFrom != nil {
+		conditions = append(conditions, fmt.Sprintf("t.updated_at >= %s", args.add(*f.UpdatedFrom)))
+	}
+	if f.UpdatedTo != nil {
+		conditions = append(conditions, fmt.Sprintf("t.updated_at <= %s", args.add(*f.UpdatedTo)))
+	}
+	if f.BudgetMin != "" {
+		conditions = append(conditions, fmt.Sprintf("t.budget_amount >= %s::text::numeric", args.add(f.BudgetMin)))
+	}
+	if f.BudgetMax != "" {
+		conditions = append(conditions, fmt.Sprintf("t.budget_amount <= %s::text::numeric", args.add(f.BudgetMax)))
+	}
+	if f.BudgetCurrency != "" {
+		conditions = append(conditions, fmt.Sprintf("t.budget_currency = %s", args.add(f.BudgetCurrency)))
+	}
+
+	sort := tenderSortColumns[f.SortField()]
+	direction, comparison := "ASC", ">"
+	if f.Descending {
+		direction, comparison = "DESC", "<"
+	}
+	if f.After != nil {
+		conditions = append(conditions, fmt.Sprintf("(%s, t.id) %s (%s::%s, %s::uuid)",
+			sort.column, comparison, args.add(f.After.Key), sort.cast, args.add(f.After.ID)))
+	}
+	orderBy := fmt.Sprintf("%s %s, t.id %s", sort.column, direction, direction)
+	if f.IsRankedBySearch() {
+		orderBy = "ts_rank(t.search_vector, search_query) DESC, t.name, t.id"
+	}
+
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM %s
+	WHERE %s
+	ORDER BY %s
+	LIMIT %s OFFSET %s
+	`, tenderColumns("t"), from, strings.Join(conditions, "\n\t  AND "), orderBy, args.add(f.Limit), args.add(f.Offset))
+	return query, args
+}
+
+type AuditFilter struct {
+	Limit          int
+	Offset         int
+	OrganizationID string
+	Action         string
+	EntityType     string
+	EntityID       string
+	ActorUsername  string
+	From           *time.Time
+	To             *time.Time
+}
+
+func (f *AuditFilter) buildQuery() (string, []any) {
+	var args queryArgs
+	conditions := []string{fmt.Sprintf("organization_id = %s", args.add(f.OrganizationID))}
+	if f.Action != "" {
+		conditions = append(conditions, fmt.Sprintf("action = %s", args.add(f.Action)))
+	}
+	if f.EntityType != "" {
+		conditions = append(conditions, fmt.Sprintf("entity_type = %s", args.add(f.EntityType)))
+	}
+	if f.EntityID != "" {
+		conditions = append(conditions, fmt.Sprintf("entity_id = %s", args.add(f.EntityID)))
+	}
+	if f.ActorUsername != "" {
+		conditions = append(conditions, fmt.Sprintf("actor_username = %s", args.add(f.ActorUsername)))
+	}
+	if f.From != nil {
+		conditions = append(conditions, fmt.Sprintf("occurred_at >= %s", args.add(*f.From)))
+	}
+	if f.To != nil {
+		conditions = append(conditions, fmt.Sprintf("occurred_at <= %s", args.add(*f.To)))
+	}
+	query := fmt.Sprintf(`
+	SELECT %s
+	FROM audit_log
+	WHERE %s
+	ORDER BY id DESC
+	LIMIT %s OFFSET %s
+	`, auditColumns, strings.Join(conditions, "\n\t  AND "), args.add(f.Limit), args.add(f.Offset))
+	return query, args
+}
@@ -3,23 +3,55 @@ package database
 import (
 	"context"
 	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pkg/errors"
 	"log/slog"
+	"strings"
+	"time"
 	"zadanie-6105/config"
 	"zadanie-6105/model"
 )
 
+const (
+	uniqueViolationCode     = "23505"
+	foreignKeyViolationCode = "23503"
+)
+
 var (
 	ErrEmployeeNotFound     = fmt.Errorf("employee not found")
 	ErrOrganizationNotFound = fmt.Errorf("organization not found")
 	ErrTenderNotFound       = fmt.Errorf("tender not found")
 	ErrTenderAlreadyExists  = fmt.Errorf("tender with same ID already exists")
+	ErrServiceTypeNotFound  = fmt.Errorf("service type not found")
+	ErrServiceTypeExists    = fmt.Errorf("service type with same name already exists")
+	ErrBidNotFound          = fmt.Errorf("bid not found")
+	ErrAttachmentNotFound   = fmt.Errorf("attachment not found")
+	ErrQuestionNotFound     = fmt.Errorf("question not found")
+	ErrInvitationNotFound   = fmt.Errorf("invitation not found")
+	ErrInvitationExists     = fmt.Errorf("organization is already invited")
+	ErrLotNotFound          = fmt.Errorf("lot not found")
+	ErrLotClosed            = fmt.Errorf("lot is already awarded or cancelled")
+	ErrTemplateNotFound     = fmt.Errorf("template not found")
+	ErrTemplateExists       = fmt.Errorf("template with same name already exists")
+	ErrWebhookNotFound      = fmt.Errorf("webhook not found")
+	ErrDeliveryNotFound     = fmt.Errorf("webhook delivery not found")
+	ErrSavedSearchNotFound  = fmt.Errorf("saved search not found")
+	ErrSavedSearchExists    = fmt.Errorf("saved search with same name already exists")
+	ErrSavedSearchLimit     = fmt.Errorf("too many saved searches")
+	ErrNotificationNotFound = fmt.Errorf("notification not found")
+	ErrBidNotPublished      = fmt.Errorf("bid is not published")
+	ErrBidDecided           = fmt.Errorf("decision on bid is already made")
+	ErrBidDecisionExists    = fmt.Errorf("employee already submitted a decision on bid")
+	ErrTenderNotPublished   = fmt.Errorf("tender is not published")
 )
 
 type postgresConnector struct {
 	DbConnector
 	pool *pgxpool.Pool
+	// db is the pool, or the transaction of a connector made by InTx.
+	db dbtx
 }
 
 func (c *postgresConnector) GetEmployeeByUsername(ctx context.Context, username string) (*model.Employee, error) {
@@ -28,7 +60,31 @@ func (c *postgresConnector) GetEmployeeByUsername(ctx context.Context, username
 	FROM employee
 	WHERE username = $1
 	`
-	rows, err := c.pool.Query(ctx, query, username)
+	rows, err := c.db.Query(ctx, query, username)
+	if err != nil {
+		slog.Warn("error db query", "error", err, "query", query)
+		return nil, errors.New("error db query")
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, ErrEmployeeNotFound
+	}
+	var employee model.Employee
+	err = rows.Scan(&employee.ID, &employee.Username, &employee.FirstName, &employee.LastName, &employee.CreatedAt, &employee.UpdatedAt)
+	if err != nil {
+		slog.Warn("error scan", "error", err)
+		return nil, errors.New("error scan")
+	}
+	return &employee, nil
+}
+
+func (c *postgresConnector) GetEmployeeByID(ctx context.Context, id string) (*model.Employee, error) {
+	query := `
+	SELECT id, username, first_name, last_name, created_at, updated_at
+	FROM employee
+	WHERE id = $1
+	`
+	rows, err := c.db.Query(ctx, query, id)
 	if err != nil {
 		slog.Warn("error db query", "error", err, "query", query)
 		return nil, errors.New("error db query")
@@ -52,7 +108,7 @@ func (c *postgresConnector) GetOrganizationById(ctx context.Context, id string)
 	FROM organization
 	WHERE id = $1
 	`
-	rows, err := c.pool.Query(ctx, query, id)
+	rows, err := c.db.Query(ctx, query, id)
 	if err != nil {
 		slog.Warn("error db query", "error", err, "query", query)
 		return nil, errors.New("error db query")
@@ -78,34 +134,27 @@ func (c *postgresConnector) IsEmployeeInOrganization(ctx context.Context, userna
                WHERE user_id = (SELECT id FROM employee WHERE employee.username = $1)
                  AND organization_id = $2)
 	`
-	rows, err := c.pool.Query(ctx, query, username, organizationID)
-	if err != nil {
-		slog.Warn("error db query", "error", err, "query", query)
-		return false, errors.New("error db query")
+	var exists bool
+	if err := c.db.QueryRow(ctx, query, username, organizationID).Scan(&exists); err != nil {
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return false, errors.New("error scanning row")
 	}
-	defer rows.Close()
-	return rows.Next(), nil
+	return exists, nil
 }
 
 func (c *postgresConnector) IsEmployeeExists(ctx context.Context, username string) (bool, error) {
 	query := `SELECT EXISTS (SELECT 1 FROM employee WHERE username = $1)`
-	rows, err := c.pool.Query(ctx, query, username)
-	if err != nil {
-		slog.Warn("error db query", "error", err, "query", query)
-		return false, errors.New("error db query")
+	var exists bool
+	if err := c.db.QueryRow(ctx, query, username).Scan(&exists); err != nil {
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return false, errors.New("error scanning row")
 	}
-	defer rows.Close()
-	return rows.Next(), nil
+	return exists, nil
 }
 
-func (c *postgresConnector) GetTenders(ctx context.Context, limit, offset int, serviceType []string) ([]model.Tender, error) {
-	query := `
-	SELECT id, name, description, service_type, status, organization_id, created_at, updated_at, creator_id, version
-	FROM tender
-	WHERE service_type = ANY($1)
-	LIMIT $2 OFFSET $3
-	`
-	rows, err := c.pool.Query(ctx, query, serviceType, limit, offset)
+func (c *postgresConnector) GetTenders(ctx context.Context, filter *TenderFilter) ([]model.Tender, error) {
+	query, args := filter.buildQuery()
+	rows, err := c.db.Query(ctx, query, args...)
 	if err != nil {
 		slog.Warn("error db query", "error", err, "query", query)
 		return nil, errors.New("error db query")
@@ -114,9 +163,7 @@ func (c *postgresConnector) GetTenders(ctx context.Context, limit, offset int, s
 	var tenders []model.Tender
 	for rows.Next() {
 		var tender model.Tender
-		err = rows.Scan(&tender.ID, &tender.Name, &tender.Description, &tender.ServiceType, &tender.Status,
-			&tender.OrganizationID, &tender.CreatedAt, &tender.UpdatedAt, &tender.CreatorID, &tender.Version)
-		if err != nil {
+		if err = scanTender(rows, &tender); err != nil {
 			slog.Warn("error scan", "error", err)
 			return nil, errors.New("error scan")
 		}
@@ -127,7 +174,7 @@ func (c *postgresConnector) GetTenders(ctx context.Context, limit, offset int, s
 
 func (c *postgresConnector) IsTenderExists(ctx context.Context, id string) (bool, error) {
 	query := `SELECT EXISTS (SELECT 1 FROM tender WHERE id = $1)`
-	rows, err := c.pool.Query(ctx, query, id)
+	rows, err := c.db.Query(ctx, query, id)
 	if err != nil {
 		slog.Warn("error db query", "error", err, "query", query)
 		return false, errors.New("error db query")
@@ -150,7 +197,7 @@ func (c *postgresConnector) IsTenderExists(ctx context.Context, id string) (bool
 
 func (c *postgresConnector) GetMaxTenderVersion(ctx context.Context, id string) (int, error) {
 	query := `SELECT MAX(version) FROM tender WHERE id = $1`
-	rows, err := c.pool.Query(ctx, query, id)
+	rows, err := c.db.Query(ctx, query, id)
 	if err != nil {
 		slog.Warn("error db query", "error", err, "query", query)
 		return 0, errors.New("error db query")
@@ -177,11 +224,11 @@ func (c *postgresConnector) GetTenderByID(ctx context.Context, id string) (*mode
 		return nil, ErrTenderNotFound
 	}
 	query := `
-	SELECT id, name, description, service_type, version,  status, organization_id, created_at, updated_at, creator_id
+	SELECT ` + tenderColumns("") + `
 	FROM tender
 	WHERE id = $1 AND version = $2
 	`
-	rows, err := c.pool.Query(ctx, query, id, maxVersion)
+	rows, err := c.db.Query(ctx, query, id, maxVersion)
 	if err != nil {
 		slog.Warn("error db query", "error", err, "query", query)
 		return nil, errors.New("error db query")
@@ -191,72 +238,142 @@ func (c *postgresConnector) GetTenderByID(ctx context.Context, id string) (*mode
 		return nil, ErrTenderNotFound
 	}
 	var tender model.Tender
-	err = rows.Scan(&tender.ID, &tender.Name, &tender.Description, &tender.ServiceType, &tender.Version, &tender.Status,
-		&tender.OrganizationID, &tender.CreatedAt, &tender.UpdatedAt, &tender.CreatorID)
-	if err != nil {
+	if err = scanTender(rows, &tender); err != nil {
 		slog.Warn("error scan", "error", err)
 		return nil, errors.New("error scan")
 	}
 	return &tender, nil
 }
 
-func (c *postgresConnector) GetTendersByCreatorID(ctx context.Context, limit, offset int, creatorID string) ([]model.Tender, error) {
-	query := `
-	SELECT id, name, description, service_type,version,  status, organization_id, created_at, updated_at, creator_id
-	FROM tender
-	WHERE creator_id = $1 
-	  AND version = (SELECT MAX(version) FROM tender as t WHERE t.id = tender.id) 
-	LIMIT $2
-	OFFSET $3
-	`
-	rows, err := c.pool.Query(ctx, query, creatorID, limit, offset)
-	if err != nil {
-		slog.Warn("error db query", "error", err, "query", query)
-		return nil, errors.New("error db query")
-	}
-	defer rows.Close()
-	var tenders []model.Tender
-	for rows.Next() {
-		var tender model.Tender
-		err = rows.Scan(&tender.ID, &tender.Name, &tender.Description, &tender.ServiceType, &tender.Version, &tender.Status,
-			&tender.OrganizationID, &tender.CreatedAt, &tender.UpdatedAt, &tender.CreatorID)
-		if err != nil {
-			slog.Warn("error scan", "error", err)
-			return nil, errors.New("error scan")
+// SaveTender inserts a new tender and the given events for it in one
+// transaction.
+func (c *postgresConnector) SaveTender(ctx context.Context, t *model.Tender, events ...model.EventType) (*model.Tender, error) {
+	err := pgx.BeginFunc(ctx, c.db, func(tx pgx.Tx) error {
+		if _, err := saveTender(ctx, tx, t); err != nil {
+			return err
 		}
-		tenders = append(tenders, tender)
+		return saveTenderEvents(ctx, tx, t, events...)
+	})
+	if err != nil {
+		return nil, err
 	}
-	return tenders, nil
+	return t, nil
+}
+
+// querier is implemented by both the pool and a transaction.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// dbtx is a querier that can start a transaction. Beginning a transaction on
+// a transaction creates a savepoint, so methods that use their own
+// transaction also work inside InTx.
+type dbtx interface {
+	querier
+	Begin(ctx context.Context) (pgx.Tx, error)
 }
 
-func (c *postgresConnector) SaveTender(ctx context.Context, t *model.Tender) (*model.Tender, error) {
+// InTx runs fn with a connector whose queries all go to one transaction. The
+// transaction is committed if fn returns nil and rolled back otherwise.
+func (c *postgresConnector) InTx(ctx context.Context, fn func(db DbConnector) error) error {
+	return pgx.BeginFunc(ctx, c.db, func(tx pgx.Tx) error {
+		return fn(&postgresConnector{pool: c.pool, db: tx})
+	})
+}
+
+func saveTender(ctx context.Context, db querier, t *model.Tender) (*model.Tender, error) {
 	query := `
-	INSERT INTO tender (name, description, service_type, status, organization_id, creator_id, version)
-	VALUES ($1, $2, $3, $4, $5, $6, $7)
-	RETURNING id, name, description, service_type, status, organization_id, creator_id, version
-	`
+	INSERT INTO tender (name, description, service_type, status, organization_id, creator_id, version, deadline, publish_at,
+	                    budget_amount, budget_currency, visibility)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10::text::numeric, $11, $12)
+	RETURNING ` + tenderColumns("")
 	if t.Version == 0 {
 		t.Version = 1
 	}
-	row := c.pool.QueryRow(ctx, query, t.Name, t.Description, t.ServiceType, t.Status, t.OrganizationID, t.CreatorID, t.Version)
-	err := row.Scan(&t.ID, &t.Name, &t.Description, &t.ServiceType, &t.Status, &t.OrganizationID, &t.CreatorID, &t.Version)
-	if err != nil {
+	if t.Visibility == "" {
+		t.Visibility = model.TenderPublic
+	}
+	budgetAmount, budgetCurrency := moneyArgs(t.Budget)
+	row := db.QueryRow(ctx, query, t.Name, t.Description, t.ServiceType, t.Status, t.OrganizationID, t.CreatorID, t.Version,
+		t.Deadline, t.PublishAt, budgetAmount, budgetCurrency, t.Visibility)
+	if err := scanTender(row, t); err != nil {
 		slog.Warn("error scanning row", "error", err, "query", query)
 		return nil, errors.New("error scanning row")
 	}
 	return t, nil
 }
 
-func (c *postgresConnector) UpdateTender(ctx context.Context, t *model.Tender) (*model.Tender, error) {
+// CloneTender saves clone as a new tender and copies the tender attachments
+// visible at the given source version and all lots of the source tender. The
+// copied attachments share blobs with the originals.
+func (c *postgresConnector) CloneTender(
+	ctx context.Context, sourceID string, sourceVersion int, clone *model.Tender, events ...model.EventType,
+) (*model.Tender, error) {
+	err := pgx.BeginFunc(ctx, c.db, func(tx pgx.Tx) error {
+		if _, err := saveTender(ctx, tx, clone); err != nil {
+			return err
+		}
+		if err := saveTenderEvents(ctx, tx, clone, events...); err != nil {
+			return err
+		}
+		attachmentsQuery := `
+		INSERT INTO attachment (id, tender_id, tender_version, file_name, content_type, size, sha256, storage_key,
+		                        uploader_id)
+		SELECT uuid_generate_v4(), $1, $2, file_name, content_type, size, sha256, storage_key, uploader_id
+		FROM attachment
+		WHERE tender_id = $3 AND tender_version <= $4 AND bid_id IS NULL
+		`
+		if _, err := tx.Exec(ctx, attachmentsQuery, clone.ID, clone.Version, sourceID, sourceVersion); err != nil {
+			slog.Warn("error db exec", "error", err, "query", attachmentsQuery)
+			return errors.New("error db exec")
+		}
+		lotsQuery := `
+		INSERT INTO tender_lot (tender_id, name, description, budget_amount, budget_currency, quantity)
+		SELECT $1, name, description, budget_amount, budget_currency, quantity
+		FROM tender_lot
+		WHERE tender_id = $2
+		ORDER BY created_at, id
+		`
+		if _, err := tx.Exec(ctx, lotsQuery, clone.ID, sourceID); err != nil {
+			slog.Warn("error db exec", "error", err, "query", lotsQuery)
+			return errors.New("error db exec")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+// UpdateTender inserts the next version of a tender and the given events for
+// it in one transaction.
+func (c *postgresConnector) UpdateTender(ctx context.Context, t *model.Tender, events ...model.EventType) (*model.Tender, error) {
+	err := pgx.BeginFunc(ctx, c.db, func(tx pgx.Tx) error {
+		if _, err := updateTender(ctx, tx, t); err != nil {
+			return err
+		}
+		return saveTenderEvents(ctx, tx, t, events...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func updateTender(ctx context.Context, db querier, t *model.Tender) (*model.Tender, error) {
 	t.Version++
 	query := `
-	INSERT INTO tender (id, name, description, service_type, status, organization_id, creator_id, version)
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-	RETURNING id, name, description, service_type, status, organization_id, creator_id, version
-	`
-	row := c.pool.QueryRow(ctx, query, t.ID, t.Name, t.Description, t.ServiceType, t.Status, t.OrganizationID, t.CreatorID, t.Version)
-	err := row.Scan(&t.ID, &t.Name, &t.Description, &t.ServiceType, &t.Status, &t.OrganizationID, &t.CreatorID, &t.Version)
-	if err != nil {
+	INSERT INTO tender (id, name, description, service_type, status, organization_id, creator_id, version, deadline,
+	                    publish_at, budget_amount, budget_currency, visibility)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11::text::numeric, $12, $13)
+	RETURNING ` + tenderColumns("")
+	budgetAmount, budgetCurrency := moneyArgs(t.Budget)
+	row := db.QueryRow(ctx, query, t.ID, t.Name, t.Description, t.ServiceType, t.Status, t.OrganizationID, t.CreatorID,
+		t.Version, t.Deadline, t.PublishAt, budgetAmount, budgetCurrency, t.Visibility)
+	if err := scanTender(row, t); err != nil {
 		slog.Warn("error scanning row", "error", err, "query", query)
 		return nil, errors.New("error scanning row")
 	}
@@ -265,11 +382,11 @@ func (c *postgresConnector) UpdateTender(ctx context.Context, t *model.Tender) (
 
 func (c *postgresConnector) GetTenderByIdAndVersion(ctx context.Context, id string, version int) (*model.Tender, error) {
 	query := `
-	SELECT id, name, description, service_type, version,  status, organization_id, created_at, updated_at, creator_id
+	SELECT ` + tenderColumns("") + `
 	FROM tender
 	WHERE id = $1 AND version = $2
 	`
-	rows, err := c.pool.Query(ctx, query, id, version)
+	rows, err := c.db.Query(ctx, query, id, version)
 	if err != nil {
 		slog.Warn("error db query", "error", err, "query", query)
 		return nil, errors.New("error db query")
@@ -279,16 +396,16 @@ func (c *postgresConnector) GetTenderByIdAndVersion(ctx context.Context, id stri
 		return nil, ErrTenderNotFound
 	}
 	var tender model.Tender
-	err = rows.Scan(&tender.ID, &tender.Name, &tender.Description, &tender.ServiceType, &tender.Version, &tender.Status,
-		&tender.OrganizationID, &tender.CreatedAt, &tender.UpdatedAt, &tender.CreatorID)
-	if err != nil {
+	if err = scanTender(rows, &tender); err != nil {
 		slog.Warn("error scan", "error", err)
 		return nil, errors.New("error scan")
 	}
 	return &tender, nil
 }
 
-func (c *postgresConnector) RollbackTender(ctx context.Context, id string, version int) (*model.Tender, error) {
+func (c *postgresConnector) RollbackTender(
+	ctx context.Context, id string, version int, events ...model.EventType,
+) (*model.Tender, error) {
 	tender, err := c.GetTenderByIdAndVersion(ctx, id, version)
 	if err != nil {
 		return nil, err
@@ -297,19 +414,2087 @@ func (c *postgresConnector) RollbackTender(ctx context.Context, id string, versi
 	if err != nil {
 		return nil, err
 	}
-	tender.Version = maxVersion + 1
-	return c.SaveTender(ctx, tender)
+	tender.Version = maxVersion
+	return c.UpdateTender(ctx, tender, events...)
 }
 
-func NewPostgresConnector(cfg *config.Config) (DbConnector, error) {
-	pgxConfig, err := pgxpool.ParseConfig(cfg.PostgresConn)
+// CloseExpiredTenders closes published tenders whose deadline has passed and
+// emits a closed event for each of them.
+func (c *postgresConnector) CloseExpiredTenders(ctx context.Context, now time.Time) ([]model.Tender, error) {
+	query := `
+	INSERT INTO tender (id, name, description, service_type, status, organization_id, creator_id, version, deadline,
+	                    publish_at, budget_amount, budget_currency, visibility)
+	SELECT t.id, t.name, t.description, t.service_type, 'Closed', t.organization_id, t.creator_id, t.version + 1,
+	       t.deadline, t.publish_at, t.budget_amount, t.budget_currency, t.visibility
+	FROM tender_current AS cur
+	JOIN tender AS t ON t.id = cur.id AND t.version = cur.version
+	WHERE t.status = 'Published'
+	  AND t.deadline IS NOT NULL
+	  AND t.deadline <= $1
+	ON CONFLICT (id, version) DO NOTHING
+	RETURNING ` + tenderColumns("")
+	return c.insertTendersWithEvent(ctx, model.EventTenderClosed, query, now.UTC())
+}
+
+// PublishScheduledTenders publishes up to limit due tenders and emits a
+// published event for each of them.
+func (c *postgresConnector) PublishScheduledTenders(ctx context.Context, now time.Time, limit int) ([]model.Tender, error) {
+	query := `
+	WITH due AS (
+		SELECT cur.id, cur.version
+		FROM tender_current AS cur
+		JOIN tender AS t ON t.id = cur.id AND t.version = cur.version
+		WHERE t.status = 'Created'
+		  AND t.publish_at IS NOT NULL
+		  AND t.publish_at <= $1
+		  AND (t.deadline IS NULL OR t.deadline > $1)
+		ORDER BY t.publish_at
+		LIMIT $2
+		FOR UPDATE OF cur SKIP LOCKED
+	)
+	INSERT INTO tender (id, name, description, service_type, status, organization_id, creator_id, version, deadline,
+	                    publish_at, budget_amount, budget_currency, visibility)
+	SELECT t.id, t.name, t.description, t.service_type, 'Published', t.organization_id, t.creator_id, t.version + 1,
+	       t.deadline, t.publish_at, t.budget_amount, t.budget_currency, t.visibility
+	FROM due
+	JOIN tender AS t ON t.id = due.id AND t.version = due.version
+	ON CONFLICT (id, version) DO NOTHING
+	RETURNING ` + tenderColumns("")
+	return c.insertTendersWithEvent(ctx, model.EventTenderPublished, query, now.UTC(), limit)
+}
+
+// insertTendersWithEvent runs an INSERT ... RETURNING of tender versions and
+// stores an event of the given type for every inserted row in the same
+// transaction.
+func (c *postgresConnector) insertTendersWithEvent(
+	ctx context.Context, eventType model.EventType, query string, args ...any,
+) ([]model.Tender, error) {
+	var tenders []model.Tender
+	err := pgx.BeginFunc(ctx, c.db, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			slog.Warn("error db query", "error", err, "query", query)
+			return errors.New("error db query")
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var tender model.Tender
+			if err = scanTender(rows, &tender); err != nil {
+				slog.Warn("error scan", "error", err)
+				return errors.New("error scan")
+			}
+			tenders = append(tenders, tender)
+		}
+		if err = rows.Err(); err != nil {
+			slog.Warn("error reading rows", "error", err, "query", query)
+			return errors.New("error reading rows")
+		}
+		for i := range tenders {
+			if err = saveTenderEvents(ctx, tx, &tenders[i], eventType); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	pgxConfig.MaxConns = maxConns
-	pool, err := pgxpool.NewWithConfig(context.Background(), pgxConfig)
+	return tenders, nil
+}
+
+func saveTenderEvents(ctx context.Context, db querier, t *model.Tender, events ...model.EventType) error {
+	query := `
+	INSERT INTO outbox_event (event_type, aggregate_type, aggregate_id, aggregate_version, payload)
+	VALUES ($1, $2, $3, $4, $5)
+	`
+	for _, eventType := range events {
+		event, err := model.NewTenderEvent(eventType, t)
+		if err != nil {
+			slog.Warn("error encoding event", "error", err, "type", eventType)
+			return errors.New("error encoding event")
+		}
+		_, err = db.Exec(ctx, query, event.Type, event.AggregateType, event.AggregateID, event.AggregateVersion, event.Payload)
+		if err != nil {
+			slog.Warn("error db exec", "error", err, "query", query)
+			return errors.New("error db exec")
+		}
+	}
+	return nil
+}
+
+func (c *postgresConnector) GetPublishedBidsByTenderID(ctx context.Context, tenderID string) ([]model.Bid, error) {
+	query := `
+	SELECT ` + bidColumns + `
+	FROM bid
+	WHERE tender_id = $1
+	  AND status = 'Published'
+	  AND version = (SELECT MAX(version) FROM bid AS b WHERE b.id = bid.id)
+	`
+	rows, err := c.db.Query(ctx, query, tenderID)
+	if err != nil {
+		slog.Warn("error db query", "error", err, "query", query)
+		return nil, errors.New("error db query")
+	}
+	defer rows.Close()
+	var bids []model.Bid
+	for rows.Next() {
+		var bid model.Bid
+		if err = scanBid(rows, &bid); err != nil {
+			slog.Warn("error scan", "error", err)
+			return nil, errors.New("error scan")
+		}
+		bids = append(bids, bid)
+	}
+	return bids, nil
+}
+
+func (c *postgresConnector) GetServiceTypes(ctx context.Context) ([]model.ServiceType, error) {
+	query := `
+	SELECT name, parent, deprecated, created_at, updated_at
+	FROM service_type
+	ORDER BY name
+	`
+	rows, err := c.db.Query(ctx, query)
+	if err != nil {
+		slog.Warn("error db query", "error", err, "query", query)
+		return nil, errors.New("error db query")
+	}
+	defer rows.Close()
+	var serviceTypes []model.ServiceType
+	for rows.Next() {
+		var st model.ServiceType
+		err = rows.Scan(&st.Name, &st.Parent, &st.Deprecated, &st.CreatedAt, &st.UpdatedAt)
+		if err != nil {
+			slog.Warn("error scan", "error", err)
+			return nil, errors.New("error scan")
+		}
+		serviceTypes = append(serviceTypes, st)
+	}
+	return serviceTypes, nil
+}
+
+func (c *postgresConnector) SaveServiceType(ctx context.Context, st *model.ServiceType) (*model.ServiceType, error) {
+	query := `
+	INSERT INTO service_type (name, parent)
+	VALUES ($1, $2)
+	RETURNING name, parent, deprecated, created_at, updated_at
+	`
+	row := c.db.QueryRow(ctx, query, st.Name, st.Parent)
+	err := row.Scan(&st.Name, &st.Parent, &st.Deprecated, &st.CreatedAt, &st.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrServiceTypeExists
+		}
+		if isForeignKeyViolation(err) {
+			return nil, ErrServiceTypeNotFound
+		}
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return st, nil
+}
+
+func (c *postgresConnector) UpdateServiceType(ctx context.Context, name string, st *model.ServiceType) (*model.ServiceType, error) {
+	query := `
+	UPDATE service_type
+	SET name = $2, parent = $3, deprecated = $4, updated_at = now()
+	WHERE name = $1
+	RETURNING name, parent, deprecated, created_at, updated_at
+	`
+	row := c.db.QueryRow(ctx, query, name, st.Name, st.Parent, st.Deprecated)
+	err := row.Scan(&st.Name, &st.Parent, &st.Deprecated, &st.CreatedAt, &st.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) || isForeignKeyViolation(err) {
+			return nil, ErrServiceTypeNotFound
+		}
+		if isUniqueViolation(err) {
+			return nil, ErrServiceTypeExists
+		}
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return st, nil
+}
+
+func (c *postgresConnector) GetBidByID(ctx context.Context, id string) (*model.Bid, error) {
+	query := `
+	SELECT ` + bidColumns + `
+	FROM bid
+	WHERE id = $1
+	ORDER BY version DESC
+	LIMIT 1
+	`
+	rows, err := c.db.Query(ctx, query, id)
+	if err != nil {
+		slog.Warn("error db query", "error", err, "query", query)
+		return nil, errors.New("error db query")
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, ErrBidNotFound
+	}
+	var bid model.Bid
+	if err = scanBid(rows, &bid); err != nil {
+		slog.Warn("error scan", "error", err)
+		return nil, errors.New("error scan")
+	}
+	return &bid, nil
+}
+
+func (c *postgresConnector) SaveBid(ctx context.Context, bid *model.Bid) (*model.Bid, error) {
+	query := `
+	INSERT INTO bid (name, description, status, tender_id, author_type, author_id, version, price_amount, price_currency,
+	                 delivery_days, terms, lot_id)
+	VALUES ($1, $2, $3, $4, $5, $6, 1, $7::text::numeric, $8, $9, $10, $11)
+	RETURNING ` + bidColumns
+	priceAmount, priceCurrency := moneyArgs(bid.Price)
+	row := c.db.QueryRow(ctx, query, bid.Name, bid.Description, bid.Status, bid.TenderId, bid.Author, bid.AuthorId,
+		priceAmount, priceCurrency, bid.DeliveryDays, nullableText(bid.Terms), bid.LotID)
+	if err := scanBid(row, bid); err != nil {
+		if isForeignKeyViolation(err) {
+			return nil, ErrTenderNotFound
+		}
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return bid, nil
+}
+
+// UpdateBid inserts the next version of a bid and the given events for it in
+// one transaction.
+func (c *postgresConnector) UpdateBid(ctx context.Context, bid *model.Bid, events ...model.EventType) (*model.Bid, error) {
+	err := pgx.BeginFunc(ctx, c.db, func(tx pgx.Tx) error {
+		if err := updateBid(ctx, tx, bid); err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		tender, err := currentTender(ctx, tx, bid.TenderId, false)
+		if err != nil {
+			return err
+		}
+		return saveBidEvents(ctx, tx, bid, tender, events...)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return &postgresConnector{pool: pool}, nil
+	return bid, nil
+}
+
+func updateBid(ctx context.Context, db querier, bid *model.Bid) error {
+	query := `
+	INSERT INTO bid (id, name, description, status, tender_id, author_type, author_id, version, created_at, price_amount,
+	                 price_currency, delivery_days, terms, lot_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10::text::numeric, $11, $12, $13, $14)
+	RETURNING ` + bidColumns
+	priceAmount, priceCurrency := moneyArgs(bid.Price)
+	row := db.QueryRow(ctx, query, bid.ID, bid.Name, bid.Description, bid.Status, bid.TenderId, bid.Author, bid.AuthorId,
+		bid.Version+1, bid.CratedAt, priceAmount, priceCurrency, bid.DeliveryDays, nullableText(bid.Terms), bid.LotID)
+	if err := scanBid(row, bid); err != nil {
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return errors.New("error scanning row")
+	}
+	return nil
+}
+
+// SaveBidDecision records the employee's decision on a published bid and
+// returns the resulting outcome, which is empty while approvals are below the
+// quorum. The decision that rejects or approves the bid emits the matching
+// event; the caller awards the bid's lot or closes the tender in the same
+// transaction. The tender row is locked, so decisions on bids of one tender
+// are made one at a time.
+func (c *postgresConnector) SaveBidDecision(
+	ctx context.Context, bid *model.Bid, employeeID string, decision model.BidDecision,
+) (model.BidDecision, error) {
+	var outcome model.BidDecision
+	err := pgx.BeginFunc(ctx, c.db, func(tx pgx.Tx) error {
+		tender, err := currentTender(ctx, tx, bid.TenderId, true)
+		if err != nil {
+			return err
+		}
+		bidQuery := `
+		SELECT ` + bidColumns + `
+		FROM bid
+		WHERE id = $1
+		ORDER BY version DESC
+		LIMIT 1
+		`
+		if err = scanBid(tx.QueryRow(ctx, bidQuery, bid.ID), bid); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrBidNotFound
+			}
+			slog.Warn("error scanning row", "error", err, "query", bidQuery)
+			return errors.New("error scanning row")
+		}
+		if bid.Status != model.BidPublished {
+			return ErrBidNotPublished
+		}
+		if tender.Status != model.TenderPublished {
+			return ErrTenderNotPublished
+		}
+		countQuery := `
+		SELECT COUNT(*) FILTER (WHERE decision = 'Rejected'),
+		       COUNT(*) FILTER (WHERE decision = 'Approved'),
+		       (SELECT LEAST(3, COUNT(*)) FROM organization_responsible WHERE organization_id = $2)
+		FROM bid_decision
+		WHERE bid_id = $1
+		`
+		var rejected, approved, quorum int
+		if err = tx.QueryRow(ctx, countQuery, bid.ID, tender.OrganizationID).Scan(&rejected, &approved, &quorum); err != nil {
+			slog.Warn("error scanning row", "error", err, "query", countQuery)
+			return errors.New("error scanning row")
+		}
+		if bidOutcome(rejected, approved, quorum) != "" {
+			return ErrBidDecided
+		}
+		insertQuery := `INSERT INTO bid_decision (bid_id, employee_id, decision) VALUES ($1, $2, $3)`
+		if _, err = tx.Exec(ctx, insertQuery, bid.ID, employeeID, decision); err != nil {
+			if isUniqueViolation(err) {
+				return ErrBidDecisionExists
+			}
+			slog.Warn("error db exec", "error", err, "query", insertQuery)
+			return errors.New("error db exec")
+		}
+		if decision == model.BidRejected {
+			rejected++
+		} else {
+			approved++
+		}
+		switch outcome = bidOutcome(rejected, approved, quorum); outcome {
+		case model.BidRejected:
+			return saveBidEvents(ctx, tx, bid, tender, model.EventBidRejected)
+		case model.BidApproved:
+			return saveBidEvents(ctx, tx, bid, tender, model.EventBidApproved)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return outcome, nil
+}
+
+func bidOutcome(rejected, approved, quorum int) model.BidDecision {
+	switch {
+	case rejected > 0:
+		return model.BidRejected
+	case quorum > 0 && approved >= quorum:
+		return model.BidApproved
+	}
+	return ""
+}
+
+// currentTender reads the current version of a tender, optionally locking it
+// for the rest of the transaction.
+func currentTender(ctx context.Context, db querier, id string, lock bool) (*model.Tender, error) {
+	query := `
+	SELECT ` + tenderColumns("t") + `
+	FROM tender_current AS cur
+	JOIN tender AS t ON t.id = cur.id AND t.version = cur.version
+	WHERE cur.id = $1
+	`
+	if lock {
+		query += "FOR UPDATE OF cur"
+	}
+	var tender model.Tender
+	if err := scanTender(db.QueryRow(ctx, query, id), &tender); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTenderNotFound
+		}
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return &tender, nil
+}
+
+func saveBidEvents(ctx context.Context, db querier, bid *model.Bid, tender *model.Tender, events ...model.EventType) error {
+	query := `
+	INSERT INTO outbox_event (event_type, aggregate_type, aggregate_id, aggregate_version, payload)
+	VALUES ($1, $2, $3, $4, $5)
+	`
+	for _, eventType := range events {
+		event, err := model.NewBidEvent(eventType, bid, tender)
+		if err != nil {
+			slog.Warn("error encoding event", "error", err, "type", eventType)
+			return errors.New("error encoding event")
+		}
+		_, err = db.Exec(ctx, query, event.Type, event.AggregateType, event.AggregateID, event.AggregateVersion, event.Payload)
+		if err != nil {
+			slog.Warn("error db exec", "error", err, "query", query)
+			return errors.New("error db exec")
+		}
+	}
+	return nil
+}
+
+func (c *postgresConnector) SaveAttachment(ctx context.Context, a *model.Attachment) (*model.Attachment, error) {
+	query := `
+	INSERT INTO attachment (id, tender_id, tender_version, bid_id, bid_version, file_name, content_type, size, sha256,
+	                        storage_key, uploader_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	RETURNING ` + attachmentColumns
+	row := c.db.QueryRow(ctx, query, a.ID, a.TenderID, a.TenderVersion, a.BidID, a.BidVersion, a.FileName, a.ContentType,
+		a.Size, a.SHA256, a.StorageKey, a.UploaderID)
+	if err := scanAttachment(row, a); err != nil {
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return a, nil
+}
+
+func (c *postgresConnector) GetAttachmentByID(ctx context.Context, id string) (*model.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachment WHERE id = $1`
+	rows, err := c.db.Query(ctx, query, id)
+	if err != nil {
+		slog.Warn("error db query", "error", err, "query", query)
+		return nil, errors.New("error db query")
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, ErrAttachmentNotFound
+	}
+	var attachment model.Attachment
+	if err = scanAttachment(rows, &attachment); err != nil {
+		slog.Warn("error scan", "error", err)
+		return nil, errors.New("error scan")
+	}
+	return &attachment, nil
+}
+
+func (c *postgresConnector) GetTenderAttachments(ctx context.Context, tenderID string, version int) ([]model.Attachment, error) {
+	query := `
+	SELECT ` + attachmentColumns + `
+	FROM attachment
+	WHERE tender_id = $1 AND tender_version <= $2 AND bid_id IS NULL
+	ORDER BY created_at, id
+	`
+	return c.queryAttachments(ctx, query, tenderID, version)
+}
+
+func (c *postgresConnector) GetBidAttachments(ctx context.Context, bidID string, version int) ([]model.Attachment, error) {
+	query := `
+	SELECT ` + attachmentColumns + `
+	FROM attachment
+	WHERE bid_id = $1 AND bid_version <= $2
+	ORDER BY created_at, id
+	`
+	return c.queryAttachments(ctx, query, bidID, version)
+}
+
+func (c *postgresConnector) queryAttachments(ctx context.Context, query string, args ...any) ([]model.Attachment, error) {
+	rows, err := c.db.Query(ctx, query, args...)
+	if err != nil {
+		slog.Warn("error db query", "error", err, "query", query)
+		return nil, errors.New("error db query")
+	}
+	defer rows.Close()
+	var attachments []model.Attachment
+	for rows.Next() {
+		var attachment model.Attachment
+		if err = scanAttachment(rows, &attachment); err != nil {
+			slog.Warn("error scan", "error", err)
+			return nil, errors.New("error scan")
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+func (c *postgresConnector) SaveQuestion(ctx context.Context, q *model.Question) (*model.Question, error) {
+	query := `
+	INSERT INTO tender_question (tender_id, author_id, text)
+	VALUES ($1, $2, $3)
+	RETURNING ` + questionColumns
+	row := c.db.QueryRow(ctx, query, q.TenderID, q.AuthorID, q.Text)
+	if err := scanQuestion(row, q); err != nil {
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return q, nil
+}
+
+func (c *postgresConnector) GetQuestionByID(ctx context.Context, id string) (*model.Question, error) {
+	query := `SELECT ` + questionColumns + ` FROM tender_question WHERE id = $1`
+	rows, err := c.db.Query(ctx, query, id)
+	if err != nil {
+		slog.Warn("error db query", "error", err, "query", query)
+		return nil, errors.New("error db query")
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, ErrQuestionNotFound
+	}
+	var question model.Question
+	if err = scanQuestion(rows, &question); err != nil {
+		slog.Warn("error scan", "error", err)
+		return nil, errors.New("error scan")
+	}
+	return &question, nil
+}
+
+func (c *postgresConnector) AnswerQuestion(ctx context.Context, q *model.Question) (*model.Question, error) {
+	query := `
+	UPDATE tender_question
+	SET answer = $2, answered_by = $3, answered_at = now(), answer_public = $4
+	WHERE id = $1
+	RETURNING ` + questionColumns
+	row := c.db.QueryRow(ctx, query, q.ID, q.Answer, q.AnsweredBy, q.AnswerPublic)
+	if err := scanQuestion(row, q); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrQuestionNotFound
+		}
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return q, nil
+}
+
+func (c *postgresConnector) GetTenderQuestions(
+	ctx context.Context, limit, offset int, tenderID, viewerID string, includePrivate bool,
+) ([]model.Question, error) {
+	query := `
+	SELECT ` + questionColumns + `
+	FROM tender_question
+	WHERE tender_id = $1
+	  AND ($3 OR author_id = $2 OR (answer IS NOT NULL AND answer_public))
+	ORDER BY created_at, id
+	LIMIT $4 OFFSET $5
+	`
+	return c.queryQuestions(ctx, query, tenderID, viewerID, includePrivate, limit, offset)
+}
+
+func (c *postgresConnector) GetQuestionsByAuthorID(ctx context.Context, limit, offset int, authorID string) ([]model.Question, error) {
+	query := `
+	SELECT ` + questionColumns + `
+	FROM tender_question
+	WHERE author_id = $1
+	ORDER BY created_at DESC, id
+	LIMIT $2 OFFSET $3
+	`
+	return c.queryQuestions(ctx, query, authorID, limit, offset)
+}
+
+func (c *postgresConnector) queryQuestions(ctx context.Context, query string, args ...any) ([]model.Question, error) {
+	rows, err := c.db.Query(ctx, query, args...)
+	if err != nil {
+		slog.Warn("error db query", "error", err, "query", query)
+		return nil, errors.New("error db query")
+	}
+	defer rows.Close()
+	var questions []model.Question
+	for rows.Next() {
+		var question model.Question
+		if err = scanQuestion(rows, &question); err != nil {
+			slog.Warn("error scan", "error", err)
+			return nil, errors.New("error scan")
+		}
+		questions = append(questions, question)
+	}
+	return questions, nil
+}
+
+func (c *postgresConnector) SaveTenderInvitation(ctx context.Context, inv *model.TenderInvitation) (*model.TenderInvitation, error) {
+	query := `
+	INSERT INTO tender_invitation (tender_id, organization_id, invited_by)
+	VALUES ($1, $2, $3)
+	RETURNING tender_id, organization_id, invited_by, created_at
+	`
+	row := c.db.QueryRow(ctx, query, inv.TenderID, inv.OrganizationID, inv.InvitedBy)
+	err := row.Scan(&inv.TenderID, &inv.OrganizationID, &inv.InvitedBy, &inv.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrInvitationExists
+		}
+		if isForeignKeyViolation(err) {
+			return nil, ErrOrganizationNotFound
+		}
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return inv, nil
+}
+
+func (c *postgresConnector) DeleteTenderInvitation(ctx context.Context, tenderID, organizationID string) error {
+	query := `
+	DELETE FROM tender_invitation
+	WHERE tender_id = $1 AND organization_id = $2
+	`
+	tag, err := c.db.Exec(ctx, query, tenderID, organizationID)
+	if err != nil {
+		slog.Warn("error db exec", "error", err, "query", query)
+		return errors.New("error db exec")
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+func (c *postgresConnector) GetTenderInvitations(ctx context.Context, tenderID string) ([]model.TenderInvitation, error) {
+	query := `
+	SELECT tender_id, organization_id, invited_by, created_at
+	FROM tender_invitation
+	WHERE tender_id = $1
+	ORDER BY created_at, organization_id
+	`
+	rows, err := c.db.Query(ctx, query, tenderID)
+	if err != nil {
+		slog.Warn("error db query", "error", err, "query", query)
+		return nil, errors.New("error db query")
+	}
+	defer rows.Close()
+	var invitations []model.TenderInvitation
+	for rows.Next() {
+		var inv model.TenderInvitation
+		if err = rows.Scan(&inv.TenderID, &inv.OrganizationID, &inv.InvitedBy, &inv.CreatedAt); err != nil {
+			slog.Warn("error scan", "error", err)
+			return nil, errors.New("error scan")
+		}
+		invitations = append(invitations, inv)
+	}
+	return invitations, nil
+}
+
+// IsTenderInvitee reports whether the employee is responsible for the tender's
+// organization or for one of the organizations invited to it.
+func (c *postgresConnector) IsTenderInvitee(ctx context.Context, tenderID, organizationID, employeeID string) (bool, error) {
+	query := `
+	SELECT EXISTS (
+		SELECT 1
+		FROM organization_responsible AS r
+		WHERE r.user_id = $3
+		  AND (r.organization_id = $2
+		       OR r.organization_id IN (SELECT organization_id FROM tender_invitation WHERE tender_id = $1))
+	)
+	`
+	var invited bool
+	if err := c.db.QueryRow(ctx, query, tenderID, organizationID, employeeID).Scan(&invited); err != nil {
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return false, errors.New("error scanning row")
+	}
+	return invited, nil
+}
+
+func (c *postgresConnector) SaveLot(ctx context.Context, lot *model.Lot) (*model.Lot, error) {
+	query := `
+	INSERT INTO tender_lot (tender_id, name, description, budget_amount, budget_currency, quantity)
+	VALUES ($1, $2, $3, $4::text::numeric, $5, $6)
+	RETURNING ` + lotColumns
+	budgetAmount, budgetCurrency := moneyArgs(lot.Budget)
+	row := c.db.QueryRow(ctx, query, lot.TenderID, lot.Name, lot.Description, budgetAmount, budgetCurrency, lot.Quantity)
+	if err := scanLot(row, lot); err != nil {
+		if isForeignKeyViolation(err) {
+			return nil, ErrTenderNotFound
+		}
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return lot, nil
+}
+
+func (c *postgresConnector) GetLotByID(ctx context.Context, id string) (*model.Lot, error) {
+	query := `
+	SELECT ` + lotColumns + `
+	FROM tender_lot
+	WHERE id = $1
+	`
+	var lot model.Lot
+	if err := scanLot(c.db.QueryRow(ctx, query, id), &lot); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrLotNotFound
+		}
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return &lot, nil
+}
+
+func (c *postgresConnector) GetTenderLots(ctx context.Context, tenderID string) ([]model.Lot, error) {
+	query := `
+	SELECT ` + lotColumns + `
+	FROM tender_lot
+	WHERE tender_id = $1
+	ORDER BY created_at, id
+	`
+	rows, err := c.db.Query(ctx, query, tenderID)
+	if err != nil {
+		slog.Warn("error db query", "error", err, "query", query)
+		return nil, errors.New("error db query")
+	}
+	defer rows.Close()
+	var lots []model.Lot
+	for rows.Next() {
+		var lot model.Lot
+		if err = scanLot(rows, &lot); err != nil {
+			slog.Warn("error scan", "error", err)
+			return nil, errors.New("error scan")
+		}
+		lots = append(lots, lot)
+	}
+	return lots, nil
+}
+
+func (c *postgresConnector) UpdateLot(ctx context.Context, lot *model.Lot) (*model.Lot, error) {
+	query := `
+	UPDATE tender_lot
+	SET name = $2, description = $3, budget_amount = $4::text::numeric, budget_currency = $5, quantity = $6,
+	    updated_at = now()
+	WHERE id = $1 AND status = 'Open'
+	RETURNING ` + lotColumns
+	budgetAmount, budgetCurrency := moneyArgs(lot.Budget)
+	row := c.db.QueryRow(ctx, query, lot.ID, lot.Name, lot.Description, budgetAmount, budgetCurrency, lot.Quantity)
+	if err := scanLot(row, lot); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrLotClosed
+		}
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return lot, nil
+}
+
+// CloseLot awards or cancels an open lot. When it was the last open lot of the
+// tender, a Closed version of the tender is inserted in the same transaction
+// and returned; otherwise the returned tender is nil.
+func (c *postgresConnector) CloseLot(
+	ctx context.Context, id string, status model.LotStatus, awardedBidID *string,
+) (*model.Lot, *model.Tender, error) {
+	var lot model.Lot
+	var closed *model.Tender
+	err := pgx.BeginFunc(ctx, c.db, func(tx pgx.Tx) error {
+		// The tender row is locked first so that lots of one tender are closed
+		// one at a time and the last one always sees the others as closed.
+		lockQuery := `
+		SELECT cur.id
+		FROM tender_lot AS l
+		JOIN tender_current AS cur ON cur.id = l.tender_id
+		WHERE l.id = $1
+		FOR UPDATE OF cur
+		`
+		var tenderID string
+		if err := tx.QueryRow(ctx, lockQuery, id).Scan(&tenderID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrLotNotFound
+			}
+			slog.Warn("error scanning row", "error", err, "query", lockQuery)
+			return errors.New("error scanning row")
+		}
+		updateQuery := `
+		UPDATE tender_lot
+		SET status = $2, awarded_bid_id = $3, updated_at = now()
+		WHERE id = $1 AND status = 'Open'
+		RETURNING ` + lotColumns
+		if err := scanLot(tx.QueryRow(ctx, updateQuery, id, status, awardedBidID), &lot); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrLotClosed
+			}
+			slog.Warn("error scanning row", "error", err, "query", updateQuery)
+			return errors.New("error scanning row")
+		}
+		var err error
+		closed, err = closeTenderIfLotsClosed(ctx, tx, tenderID)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &lot, closed, nil
+}
+
+// CloseTenderIfLotsClosed closes a tender that has no open lot left and
+// returns the Closed version, or nil when the tender is already closed or
+// still has an open lot.
+func (c *postgresConnector) CloseTenderIfLotsClosed(ctx context.Context, id string) (*model.Tender, error) {
+	var closed *model.Tender
+	err := pgx.BeginFunc(ctx, c.db, func(tx pgx.Tx) error {
+		if _, err := currentTender(ctx, tx, id, true); err != nil {
+			return err
+		}
+		var err error
+		closed, err = closeTenderIfLotsClosed(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return closed, nil
+}
+
+// closeTenderIfLotsClosed inserts a Closed version of the tender with its
+// closed event. The caller holds the lock on the tender.
+func closeTenderIfLotsClosed(ctx context.Context, db querier, id string) (*model.Tender, error) {
+	query := `
+	INSERT INTO tender (id, name, description, service_type, status, organization_id, creator_id, version, deadline,
+	                    publish_at, budget_amount, budget_currency, visibility)
+	SELECT t.id, t.name, t.description, t.service_type, 'Closed', t.organization_id, t.creator_id, t.version + 1,
+	       t.deadline, t.publish_at, t.budget_amount, t.budget_currency, t.visibility
+	FROM tender_current AS cur
+	JOIN tender AS t ON t.id = cur.id AND t.version = cur.version
+	WHERE cur.id = $1
+	  AND t.status <> 'Closed'
+	  AND NOT EXISTS (SELECT 1 FROM tender_lot WHERE tender_id = $1 AND status = 'Open')
+	RETURNING ` + tenderColumns("")
+	var tender model.Tender
+	if err := scanTender(db.QueryRow(ctx, query, id), &tender); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	if err := saveTenderEvents(ctx, db, &tender, model.EventTenderClosed); err != nil {
+		return nil, err
+	}
+	return &tender, nil
+}
+
+func (c *postgresConnector) SaveTemplate(ctx context.Context, tpl *model.TenderTemplate) (*model.TenderTemplate, error) {
+	query := `
+	INSERT INTO tender_template (organization_id, name, tender_name, tender_description, service_type, visibility,
+	                             budget_amount, budget_currency, creator_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7::text::numeric, $8, $9)
+	RETURNING ` + templateColumns
+	if tpl.Visibility == "" {
+		tpl.Visibility = model.TenderPublic
+	}
+	budgetAmount, budgetCurrency := moneyArgs(tpl.Budget)
+	row := c.db.QueryRow(ctx, query, tpl.OrganizationID, tpl.Name, tpl.TenderName, tpl.TenderDescription,
+		tpl.ServiceType, tpl.Visibility, budgetAmount, budgetCurrency, tpl.CreatorID)
+	if err := scanTemplate(row, tpl); err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrTemplateExists
+		}
+		if isForeignKeyViolation(err) {
+			return nil, ErrOrganizationNotFound
+		}
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return tpl, nil
+}
+
+func (c *postgresConnector) GetTemplateByID(ctx context.Context, id string) (*model.TenderTemplate, error) {
+	query := `
+	SELECT ` + templateColumns + `
+	FROM tender_template
+	WHERE id = $1
+	`
+	var tpl model.TenderTemplate
+	if err := scanTemplate(c.db.QueryRow(ctx, query, id), &tpl); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTemplateNotFound
+		}
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return &tpl, nil
+}
+
+func (c *postgresConnector) GetOrganizationTemplates(
+	ctx context.Context, limit, offset int, organizationID string,
+) ([]model.TenderTemplate, error) {
+	query := `
+	SELECT ` + templateColumns + `
+	FROM tender_template
+	WHERE organization_id = $1
+	ORDER BY name
+	LIMIT $2 OFFSET $3
+	`
+	rows, err := c.db.Query(ctx, query, organizationID, limit, offset)
+	if err != nil {
+		slog.Warn("error db query", "error", err, "query", query)
+		return nil, errors.New("error db query")
+	}
+	defer rows.Close()
+	var templates []model.TenderTemplate
+	for rows.Next() {
+		var tpl model.TenderTemplate
+		if err = scanTemplate(rows, &tpl); err != nil {
+			slog.Warn("error scan", "error", err)
+			return nil, errors.New("error scan")
+		}
+		templates = append(templates, tpl)
+	}
+	return templates, nil
+}
+
+func (c *postgresConnector) UpdateTemplate(ctx context.Context, tpl *model.TenderTemplate) (*model.TenderTemplate, error) {
+	query := `
+	UPDATE tender_template
+	SET name = $2, tender_name = $3, tender_description = $4, service_type = $5, visibility = $6,
+	    budget_amount = $7::text::numeric, budget_currency = $8, updated_at = now()
+	WHERE id = $1
+	RETURNING ` + templateColumns
+	budgetAmount, budgetCurrency := moneyArgs(tpl.Budget)
+	row := c.db.QueryRow(ctx, query, tpl.ID, tpl.Name, tpl.TenderName, tpl.TenderDescription, tpl.ServiceType,
+		tpl.Visibility, budgetAmount, budgetCurrency)
+	if err := scanTemplate(row, tpl); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTemplateNotFound
+		}
+		if isUniqueViolation(err) {
+			return nil, ErrTemplateExists
+		}
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return tpl, nil
+}
+
+func (c *postgresConnector) DeleteTemplate(ctx context.Context, id string) error {
+	query := `
+	DELETE FROM tender_template
+	WHERE id = $1
+	`
+	tag, err := c.db.Exec(ctx, query, id)
+	if err != nil {
+		slog.Warn("error db exec", "error", err, "query", query)
+		return errors.New("error db exec")
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+// SaveAuditEntry appends an entry to the audit log. The actor is resolved from
+// the username when only the username is known.
+func (c *postgresConnector) SaveAuditEntry(ctx context.Context, e *model.AuditEntry) (*model.AuditEntry, error) {
+	query := `
+	INSERT INTO audit_log (actor_id, actor_username, action, entity_type, entity_id, entity_version, organization_id,
+	                       request_id, client_ip, before, after)
+	VALUES (COALESCE($1, (SELECT id FROM employee WHERE username = $2)), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	RETURNING ` + auditColumns
+	row := c.db.QueryRow(ctx, query, e.ActorID, e.ActorUsername, e.Action, e.EntityType, e.EntityID, e.EntityVersion,
+		e.OrganizationID, e.RequestID, e.ClientIP, e.Before, e.After)
+	if err := scanAuditEntry(row, e); err != nil {
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return e, nil
+}
+
+func (c *postgresConnector) GetAuditEntries(ctx context.Context, filter *AuditFilter) ([]model.AuditEntry, error) {
+	query, args := filter.buildQuery()
+	rows, err := c.db.Query(ctx, query, args...)
+	if err != nil {
+		slog.Warn("error db query", "error", err, "query", query)
+		return nil, errors.New("error db query")
+	}
+	defer rows.Close()
+	var entries []model.AuditEntry
+	for rows.Next() {
+		var entry model.AuditEntry
+		if err = scanAuditEntry(rows, &entry); err != nil {
+			slog.Warn("error scan", "error", err)
+			return nil, errors.New("error scan")
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+const (
+	ChainTenders = "tender"
+	ChainBids    = "bid"
+	ChainAudit   = "audit_log"
+)
+
+var chainQueries = map[string]string{
+	ChainTenders: `
+	SELECT id::text, id::text, version, prev_hash, hash, tender_chain_content(t)
+	FROM tender AS t
+	ORDER BY id, version
+	`,
+	ChainBids: `
+	SELECT id::text, id::text, version, prev_hash, hash, bid_chain_content(b)
+	FROM bid AS b
+	ORDER BY id, version
+	`,
+	ChainAudit: `
+	SELECT '', id::text, id, prev_hash, hash, audit_log_chain_content(a)
+	FROM audit_log AS a
+	ORDER BY id
+	`,
+}
+
+// ScanChain streams the links of a hash chain in chain order to fn and stops
+// at the first error returned by fn.
+func (c *postgresConnector) ScanChain(ctx context.Context, chain string, fn func(link *model.ChainLink) error) error {
+	query, ok := chainQueries[chain]
+	if !ok {
+		return fmt.Errorf("unknown chain %q", chain)
+	}
+	rows, err := c.db.Query(ctx, query)
+	if err != nil {
+		slog.Warn("error db query", "error", err, "query", query)
+		return errors.New("error db query")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var link model.ChainLink
+		if err = rows.Scan(&link.Group, &link.ID, &link.Seq, &link.PrevHash, &link.Hash, &link.Content); err != nil {
+			slog.Warn("error scan", "error", err)
+			return errors.New("error scan")
+		}
+		if err = fn(&link); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		slog.Warn("error reading rows", "error", err, "query", query)
+		return errors.New("error reading rows")
+	}
+	return nil
+}
+
+// GetPendingOutboxDeliveries returns up to limit events that sink has not
+// delivered or given up on yet, in the order they were stored, including ones
+// whose next attempt is not due yet.
+func (c *postgresConnector) GetPendingOutboxDeliveries(ctx context.Context, sink string, limit int) ([]model.OutboxDelivery, error) {
+	query := `
+	SELECT ` + outboxColumns("e") + `, $1::varchar, COALESCE(d.attempts, 0), COALESCE(d.next_attempt_at, e.created_at),
+	       d.last_error
+	FROM outbox_event AS e
+	LEFT JOIN outbox_delivery AS d ON d.event_id = e.id AND d.sink = $1
+	WHERE e.status = 'Pending'
+	  AND (d.status IS NULL OR d.status = 'Pending')
+	ORDER BY e.id
+	LIMIT $2
+	`
+	rows, err := c.db.Query(ctx, query, sink, limit)
+	if err != nil {
+		slog.Warn("error db query", "error", err, "query", query)
+		return nil, errors.New("error db query")
+	}
+	defer rows.Close()
+	var deliveries []model.OutboxDelivery
+	for rows.Next() {
+		var delivery model.OutboxDelivery
+		if err = scanOutboxDelivery(rows, &delivery); err != nil {
+			slog.Warn("error scan", "error", err)
+			return nil, errors.New("error scan")
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+// GetOutboxEvents returns up to limit events with afterID < id <= upToID in
+// id order, whatever their delivery status.
+func (c *postgresConnector) GetOutboxEvents(ctx context.Context, afterID, upToID int64, limit int) ([]model.OutboxEvent, error) {
+	query := `
+	SELECT ` + outboxColumns("") + `
+	FROM outbox_event
+	WHERE id > $1 AND id <= $2
+	ORDER BY id
+	LIMIT $3
+	`
+	rows, err := c.db.Query(ctx, query, afterID, upToID, limit)
+	if err != nil {
+		slog.Warn("error db query", "error", err, "query", query)
+		return nil, errors.New("error db query")
+	}
+	defer rows.Close()
+	var events []model.OutboxEvent
+	for rows.Next() {
+		var event model.OutboxEvent
+		if err = scanOutboxEvent(rows, &event); err != nil {
+			slog.Warn("error scan", "error", err)
+			return nil, errors.New("error scan")
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (c *postgresConnector) GetLastOutboxEventID(ctx context.Context) (int64, error) {
+	query := `SELECT COALESCE(MAX(id), 0) FROM outbox_event`
+	var id int64
+	if err := c.db.QueryRow(ctx, query).Scan(&id); err != nil {
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return 0, errors.New("error scanning row")
+	}
+	return id, nil
+}
+
+// ListenOutboxEvents holds a connection listening for outbox inserts and calls
+// notify for every notification until ctx is done or the connection fails.
+func (c *postgresConnector) ListenOutboxEvents(ctx context.Context, notify func()) error {
+	conn, err := c.pool.Acquire(ctx)
+	if err != nil {
+		slog.Warn("error acquiring connection", "error", err)
+		return errors.New("error acquiring connection")
+	}
+	defer func() {
+		_, _ = conn.Exec(context.Background(), "UNLISTEN *")
+		conn.Release()
+	}()
+	if _, err = conn.Exec(ctx, "LISTEN outbox_event"); err != nil {
+		slog.Warn("error db exec", "error", err, "query", "LISTEN outbox_event")
+		return errors.New("error db exec")
+	}
+	for {
+		if _, err = conn.Conn().WaitForNotification(ctx); err != nil {
+			return err
+		}
+		notify()
+	}
+}
+
+// MarkOutboxDelivered records that sink delivered the event. sinks are all
+// configured sinks; the event itself is marked once each of them is done.
+func (c *postgresConnector) MarkOutboxDelivered(ctx context.Context, eventID int64, sink string, sinks []string) error {
+	return pgx.BeginFunc(ctx, c.db, func(tx pgx.Tx) error {
+		if err := saveOutboxDelivery(ctx, tx, eventID, sink, model.OutboxDelivered, time.Now(), nil); err != nil {
+			return err
+		}
+		return completeOutboxEvent(ctx, tx, eventID, sinks)
+	})
+}
+
+func (c *postgresConnector) RetryOutboxDelivery(
+	ctx context.Context, eventID int64, sink string, nextAttemptAt time.Time, lastError string,
+) error {
+	return saveOutboxDelivery(ctx, c.db, eventID, sink, model.OutboxPending, nextAttemptAt, &lastError)
+}
+
+// DeadLetterOutboxDelivery records that sink gave up on the event. Other sinks
+// keep delivering it; the event becomes DeadLetter once all of them are done.
+func (c *postgresConnector) DeadLetterOutboxDelivery(
+	ctx context.Context, eventID int64, sink, lastError string, sinks []string,
+) error {
+	return pgx.BeginFunc(ctx, c.db, func(tx pgx.Tx) error {
+		if err := saveOutboxDelivery(ctx, tx, eventID, sink, model.OutboxDeadLetter, time.Now(), &lastError); err != nil {
+			return err
+		}
+		return completeOutboxEvent(ctx, tx, eventID, sinks)
+	})
+}
+
+func saveOutboxDelivery(
+	ctx context.Context, db querier, eventID int64, sink string, status model.OutboxStatus, nextAttemptAt time.Time,
+	lastError *string,
+) error {
+	query := `
+	INSERT INTO outbox_delivery (event_id, sink, status, attempts, next_attempt_at, last_error, delivered_at)
+	VALUES ($1, $2, $3::outbox_status, 1, $4, $5, CASE WHEN $3::outbox_status = 'Delivered' THEN now() END)
+	ON CONFLICT (event_id, sink) DO UPDATE
+	SET status          = excluded.status,
+	    attempts        = outbox_delivery.attempts + 1,
+	    next_attempt_at = excluded.next_attempt_at,
+	    last_error      = excluded.last_error,
+	    delivered_at    = excluded.delivered_at
+	`
+	if _, err := db.Exec(ctx, query, eventID, sink, status, nextAttemptAt, lastError); err != nil {
+		slog.Warn("error db exec", "error", err, "query", query)
+		return errors.New("error db exec")
+	}
+	return nil
+}
+
+// completeOutboxEvent moves the event out of Pending when none of sinks still
+// has it pending: to DeadLetter if any of them gave up, to Delivered otherwise.
+func completeOutboxEvent(ctx context.Context, db querier, eventID int64, sinks []string) error {
+	query := `
+	WITH done AS (SELECT d.status
+	              FROM outbox_delivery AS d
+	              WHERE d.event_id = $1
+	                AND d.sink = ANY ($2::varchar[])
+	                AND d.status <> 'Pending')
+	UPDATE outbox_event
+	SET status       = CASE WHEN EXISTS (SELECT 1 FROM done WHERE status = 'DeadLetter')
+	                            THEN 'DeadLetter'::outbox_status
+	                        ELSE 'Delivered'::outbox_status END,
+	    delivered_at = now()
+	WHERE id = $1
+	  AND status = 'Pending'
+	  AND (SELECT count(*) FROM done) = cardinality($2::varchar[])
+	`
+	if _, err := db.Exec(ctx, query, eventID, sinks); err != nil {
+		slog.Warn("error db exec", "error", err, "query", query)
+		return errors.New("error db exec")
+	}
+	return nil
+}
+
+// RunExclusive runs fn while holding a transaction-scoped advisory lock named
+// name. It returns false without calling fn when another instance holds the
+// lock.
+func (c *postgresConnector) RunExclusive(ctx context.Context, name string, fn func(ctx context.Context) error) (bool, error) {
+	query := `SELECT pg_try_advisory_xact_lock(hashtext($1))`
+	var locked bool
+	err := pgx.BeginFunc(ctx, c.db, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, query, name).Scan(&locked); err != nil {
+			slog.Warn("error scanning row", "error", err, "query", query)
+			return errors.New("error scanning row")
+		}
+		if !locked {
+			return nil
+		}
+		return fn(ctx)
+	})
+	return locked, err
+}
+
+func (c *postgresConnector) SaveWebhook(ctx context.Context, hook *model.Webhook) (*model.Webhook, error) {
+	query := `
+	INSERT INTO webhook (organization_id, url, event_types, secret, active, creator_id)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING ` + webhookColumns
+	row := c.db.QueryRow(ctx, query, hook.OrganizationID, hook.URL, eventTypeArgs(hook.EventTypes), hook.Secret,
+		hook.Active, hook.CreatorID)
+	if err := scanWebhook(row, hook); err != nil {
+		if isForeignKeyViolation(err) {
+			return nil, ErrOrganizationNotFound
+		}
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return hook, nil
+}
+
+func (c *postgresConnector) GetWebhookByID(ctx context.Context, id string) (*model.Webhook, error) {
+	query := `
+	SELECT ` + webhookColumns + `
+	FROM webhook
+	WHERE id = $1
+	`
+	var hook model.Webhook
+	if err := scanWebhook(c.db.QueryRow(ctx, query, id), &hook); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return &hook, nil
+}
+
+func (c *postgresConnector) GetOrganizationWebhooks(
+	ctx context.Context, limit, offset int, organizationID string,
+) ([]model.Webhook, error) {
+	query := `
+	SELECT ` + webhookColumns + `
+	FROM webhook
+	WHERE organization_id = $1
+	ORDER BY created_at
+	LIMIT $2 OFFSET $3
+	`
+	rows, err := c.db.Query(ctx, query, organizationID, limit, offset)
+	if err != nil {
+		slog.Warn("error db query", "error", err, "query", query)
+		return nil, errors.New("error db query")
+	}
+	defer rows.Close()
+	var hooks []model.Webhook
+	for rows.Next() {
+		var hook model.Webhook
+		if err = scanWebhook(rows, &hook); err != nil {
+			slog.Warn("error scan", "error", err)
+			return nil, errors.New("error scan")
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
+
+func (c *postgresConnector) UpdateWebhook(ctx context.Context, hook *model.Webhook) (*model.Webhook, error) {
+	query := `
+	UPDATE webhook
+	SET url = $2, event_types = $3, secret = $4, active = $5, updated_at = now()
+	WHERE id = $1
+	RETURNING ` + webhookColumns
+	row := c.db.QueryRow(ctx, query, hook.ID, hook.URL, eventTypeArgs(hook.EventTypes), hook.Secret, hook.Active)
+	if err := scanWebhook(row, hook); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return hook, nil
+}
+
+func (c *postgresConnector) DeleteWebhook(ctx context.Context, id string) error {
+	query := `
+	DELETE FROM webhook
+	WHERE id = $1
+	`
+	tag, err := c.db.Exec(ctx, query, id)
+	if err != nil {
+		slog.Warn("error db exec", "error", err, "query", query)
+		return errors.New("error db exec")
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// SaveWebhookDeliveries queues the event for every active webhook subscribed
+// to its type whose organization may see the tender version, by the rules of
+// the tender list: the tender owner always, other organizations only while it
+// is published, and for invite-only tenders only when invited. Queuing the same event twice is a no-op.
+func (c *postgresConnector) SaveWebhookDeliveries(ctx context.Context, eventID int64) (int, error) {
+	query := `
+	INSERT INTO webhook_delivery (webhook_id, event_id, event_type, payload)
+	SELECT w.id, e.id, e.event_type,
+	       jsonb_build_object('id', e.id, 'type', e.event_type, 'occurredAt', e.created_at, 'data', e.payload)
+	FROM outbox_event AS e
+	JOIN webhook AS w ON w.active AND e.event_type = ANY (w.event_types)
+	WHERE e.id = $1
+	  AND e.aggregate_type = 'tender'
+	  AND (w.organization_id = (e.payload ->> 'organizationId')::uuid
+	    OR e.payload ->> 'status' = 'Published'
+	       AND (e.payload ->> 'visibility' = 'Public'
+	         OR EXISTS (SELECT 1
+	                    FROM tender_invitation AS i
+	                    WHERE i.tender_id = e.aggregate_id
+	                      AND i.organization_id = w.organization_id)))
+	ON CONFLICT (webhook_id, event_id) WHERE redelivery_of IS NULL DO NOTHING
+	`
+	tag, err := c.db.Exec(ctx, query, eventID)
+	if err != nil {
+		slog.Warn("error db exec", "error", err, "query", query)
+		return 0, errors.New("error db exec")
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// GetDueWebhookDeliveries returns pending deliveries of active webhooks whose
+// next attempt is due, oldest first.
+func (c *postgresConnector) GetDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]model.WebhookDelivery, error) {
+	query := `
+	SELECT ` + deliveryColumns("d") + `
+	FROM webhook_delivery AS d
+	JOIN webhook AS w ON w.id = d.webhook_id
+	WHERE d.status = 'Pending'
+	  AND d.next_attempt_at <= $1
+	  AND w.active
+	ORDER BY d.next_attempt_at
+	LIMIT $2
+	`
+	return c.queryDeliveries(ctx, query, now.UTC(), limit)
+}
+
+func (c *postgresConnector) GetWebhookDeliveries(
+	ctx context.Context, limit, offset int, webhookID string,
+) ([]model.WebhookDelivery, error) {
+	query := `
+	SELECT ` + deliveryColumns("") + `
+	FROM webhook_delivery
+	WHERE webhook_id = $1
+	ORDER BY created_at DESC
+	LIMIT $2 OFFSET $3
+	`
+	return c.queryDeliveries(ctx, query, webhookID, limit, offset)
+}
+
+func (c *postgresConnector) queryDeliveries(ctx context.Context, query string, args ...any) ([]model.WebhookDelivery, error) {
+	rows, err := c.db.Query(ctx, query, args...)
+	if err != nil {
+		slog.Warn("error db query", "error", err, "query", query)
+		return nil, errors.New("error db query")
+	}
+	defer rows.Close()
+	var deliveries []model.WebhookDelivery
+	for rows.Next() {
+		var delivery model.WebhookDelivery
+		if err = scanDelivery(rows, &delivery); err != nil {
+			slog.Warn("error scan", "error", err)
+			return nil, errors.New("error scan")
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+func (c *postgresConnector) GetWebhookDeliveryByID(ctx context.Context, id string) (*model.WebhookDelivery, error) {
+	query := `
+	SELECT ` + deliveryColumns("") + `
+	FROM webhook_delivery
+	WHERE id = $1
+	`
+	var delivery model.WebhookDelivery
+	if err := scanDelivery(c.db.QueryRow(ctx, query, id), &delivery); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDeliveryNotFound
+		}
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return &delivery, nil
+}
+
+// UpdateWebhookDelivery stores the outcome of a delivery attempt.
+func (c *postgresConnector) UpdateWebhookDelivery(ctx context.Context, d *model.WebhookDelivery) error {
+	query := `
+	UPDATE webhook_delivery
+	SET status = $2, attempts = $3, next_attempt_at = $4, last_status_code = $5, last_error = $6, delivered_at = $7
+	WHERE id = $1
+	`
+	_, err := c.db.Exec(ctx, query, d.ID, d.Status, d.Attempts, d.NextAttemptAt.UTC(), d.LastStatusCode, d.LastError,
+		d.DeliveredAt)
+	if err != nil {
+		slog.Warn("error db exec", "error", err, "query", query)
+		return errors.New("error db exec")
+	}
+	return nil
+}
+
+// RedeliverWebhookDelivery queues a new delivery with the same payload as the
+// given one. The original delivery and its history are left untouched.
+func (c *postgresConnector) RedeliverWebhookDelivery(ctx context.Context, id string) (*model.WebhookDelivery, error) {
+	query := `
+	INSERT INTO webhook_delivery (webhook_id, event_id, event_type, payload, redelivery_of)
+	SELECT webhook_id, event_id, event_type, payload, id
+	FROM webhook_delivery
+	WHERE id = $1
+	RETURNING ` + deliveryColumns("")
+	var delivery model.WebhookDelivery
+	if err := scanDelivery(c.db.QueryRow(ctx, query, id), &delivery); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDeliveryNotFound
+		}
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return &delivery, nil
+}
+
+// SaveSavedSearch stores the search unless the employee already has
+// maxPerEmployee of them.
+func (c *postgresConnector) SaveSavedSearch(
+	ctx context.Context, search *model.SavedSearch, maxPerEmployee int,
+) (*model.SavedSearch, error) {
+	query := `
+	INSERT INTO saved_search (employee_id, name, service_types, keywords, budget_min, budget_max, budget_currency)
+	SELECT $1, $2, $3, $4, $5::text::numeric, $6::text::numeric, $7
+	WHERE (SELECT count(*) FROM saved_search WHERE employee_id = $1) < $8
+	RETURNING ` + savedSearchColumns
+	row := c.db.QueryRow(ctx, query, search.EmployeeID, search.Name, search.ServiceTypes, search.Keywords,
+		search.BudgetMin, search.BudgetMax, search.BudgetCurrency, maxPerEmployee)
+	if err := scanSavedSearch(row, search); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrSavedSearchLimit
+		}
+		if isUniqueViolation(err) {
+			return nil, ErrSavedSearchExists
+		}
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return search, nil
+}
+
+func (c *postgresConnector) GetSavedSearchByID(ctx context.Context, id string) (*model.SavedSearch, error) {
+	query := `
+	SELECT ` + savedSearchColumns + `
+	FROM saved_search
+	WHERE id = $1
+	`
+	var search model.SavedSearch
+	if err := scanSavedSearch(c.db.QueryRow(ctx, query, id), &search); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrSavedSearchNotFound
+		}
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return &search, nil
+}
+
+func (c *postgresConnector) GetEmployeeSavedSearches(
+	ctx context.Context, limit, offset int, employeeID string,
+) ([]model.SavedSearch, error) {
+	query := `
+	SELECT ` + savedSearchColumns + `
+	FROM saved_search
+	WHERE employee_id = $1
+	ORDER BY name
+	LIMIT $2 OFFSET $3
+	`
+	rows, err := c.db.Query(ctx, query, employeeID, limit, offset)
+	if err != nil {
+		slog.Warn("error db query", "error", err, "query", query)
+		return nil, errors.New("error db query")
+	}
+	defer rows.Close()
+	var searches []model.SavedSearch
+	for rows.Next() {
+		var search model.SavedSearch
+		if err = scanSavedSearch(rows, &search); err != nil {
+			slog.Warn("error scan", "error", err)
+			return nil, errors.New("error scan")
+		}
+		searches = append(searches, search)
+	}
+	return searches, nil
+}
+
+func (c *postgresConnector) DeleteSavedSearch(ctx context.Context, id string) error {
+	query := `
+	DELETE FROM saved_search
+	WHERE id = $1
+	`
+	tag, err := c.db.Exec(ctx, query, id)
+	if err != nil {
+		slog.Warn("error db exec", "error", err, "query", query)
+		return errors.New("error db exec")
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSavedSearchNotFound
+	}
+	return nil
+}
+
+// SaveTenderMatchNotifications notifies every employee with a saved search
+// matching the given published tender version, at most once per tender. The
+// tender creator and employees who cannot see the tender are skipped.
+func (c *postgresConnector) SaveTenderMatchNotifications(ctx context.Context, tenderID string, version int) (int, error) {
+	query := `
+	WITH RECURSIVE published AS (
+		SELECT *
+		FROM tender
+		WHERE id = $1 AND version = $2 AND status = 'Published'
+	), ancestors AS (
+		SELECT st.name, st.parent
+		FROM service_type AS st
+		JOIN published AS t ON t.service_type = st.name
+		UNION
+		SELECT st.name, st.parent
+		FROM service_type AS st
+		JOIN ancestors AS a ON st.name = a.parent
+	)
+	INSERT INTO notification (employee_id, type, tender_id, tender_version, saved_search_id, title)
+	SELECT DISTINCT ON (s.employee_id) s.employee_id, $3, t.id, t.version, s.id, t.name
+	FROM saved_search AS s
+	CROSS JOIN published AS t
+	WHERE s.employee_id <> t.creator_id
+	  AND (cardinality(s.service_types) = 0
+	    OR EXISTS (SELECT 1 FROM ancestors AS a WHERE a.name = ANY (s.service_types)))
+	  AND (s.keywords IS NULL
+	    OR t.search_vector @@ (websearch_to_tsquery('russian', s.keywords) || websearch_to_tsquery('english', s.keywords)))
+	  AND (s.budget_min IS NULL OR t.budget_amount >= s.budget_min)
+	  AND (s.budget_max IS NULL OR t.budget_amount <= s.budget_max)
+	  AND (s.budget_currency IS NULL OR t.budget_currency = s.budget_currency)
+	  AND (t.visibility = 'Public'
+	    OR EXISTS (SELECT 1
+	               FROM organization_responsible AS r
+	               WHERE r.user_id = s.employee_id
+	                 AND (r.organization_id = t.organization_id
+	                   OR r.organization_id IN (SELECT organization_id FROM tender_invitation WHERE tender_id = t.id))))
+	ORDER BY s.employee_id, s.created_at
+	ON CONFLICT (employee_id, type, tender_id) DO NOTHING
+	`
+	tag, err := c.db.Exec(ctx, query, tenderID, version, model.NotificationTenderMatched)
+	if err != nil {
+		slog.Warn("error db exec", "error", err, "query", query)
+		return 0, errors.New("error db exec")
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func (c *postgresConnector) GetNotifications(
+	ctx context.Context, limit, offset int, employeeID string, unreadOnly bool,
+) ([]model.Notification, error) {
+	query := `
+	SELECT ` + notificationColumns + `
+	FROM notification
+	WHERE employee_id = $1
+	  AND (NOT $2 OR read_at IS NULL)
+	ORDER BY created_at DESC, id
+	LIMIT $3 OFFSET $4
+	`
+	rows, err := c.db.Query(ctx, query, employeeID, unreadOnly, limit, offset)
+	if err != nil {
+		slog.Warn("error db query", "error", err, "query", query)
+		return nil, errors.New("error db query")
+	}
+	defer rows.Close()
+	var notifications []model.Notification
+	for rows.Next() {
+		var notification model.Notification
+		if err = scanNotification(rows, &notification); err != nil {
+			slog.Warn("error scan", "error", err)
+			return nil, errors.New("error scan")
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, nil
+}
+
+func (c *postgresConnector) CountUnreadNotifications(ctx context.Context, employeeID string) (int, error) {
+	query := `
+	SELECT count(*)
+	FROM notification
+	WHERE employee_id = $1 AND read_at IS NULL
+	`
+	var count int
+	if err := c.db.QueryRow(ctx, query, employeeID).Scan(&count); err != nil {
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return 0, errors.New("error scanning row")
+	}
+	return count, nil
+}
+
+// MarkNotificationRead marks one of the employee's notifications as read.
+// Marking an already read notification keeps its original read time.
+func (c *postgresConnector) MarkNotificationRead(ctx context.Context, id, employeeID string) (*model.Notification, error) {
+	query := `
+	UPDATE notification
+	SET read_at = COALESCE(read_at, now())
+	WHERE id = $1 AND employee_id = $2
+	RETURNING ` + notificationColumns
+	var notification model.Notification
+	if err := scanNotification(c.db.QueryRow(ctx, query, id, employeeID), &notification); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotificationNotFound
+		}
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return &notification, nil
+}
+
+func (c *postgresConnector) MarkAllNotificationsRead(ctx context.Context, employeeID string) (int, error) {
+	query := `
+	UPDATE notification
+	SET read_at = now()
+	WHERE employee_id = $1 AND read_at IS NULL
+	`
+	tag, err := c.db.Exec(ctx, query, employeeID)
+	if err != nil {
+		slog.Warn("error db exec", "error", err, "query", query)
+		return 0, errors.New("error db exec")
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// GetEmailPreferences returns the employee's email settings, or the defaults
+// (no address, all notifications on) when they were never saved.
+func (c *postgresConnector) GetEmailPreferences(ctx context.Context, employeeID string) (*model.EmailPreferences, error) {
+	query := `
+	SELECT ` + emailPreferenceColumns + `
+	FROM email_preference
+	WHERE employee_id = $1
+	`
+	prefs := model.EmailPreferences{EmployeeID: employeeID, BidSubmitted: true, BidDecided: true}
+	if err := scanEmailPreferences(c.db.QueryRow(ctx, query, employeeID), &prefs); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &prefs, nil
+		}
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return &prefs, nil
+}
+
+func (c *postgresConnector) SaveEmailPreferences(
+	ctx context.Context, prefs *model.EmailPreferences,
+) (*model.EmailPreferences, error) {
+	query := `
+	INSERT INTO email_preference (employee_id, email, bid_submitted, bid_decided)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (employee_id) DO UPDATE
+	SET email = excluded.email, bid_submitted = excluded.bid_submitted, bid_decided = excluded.bid_decided,
+	    updated_at = now()
+	RETURNING ` + emailPreferenceColumns
+	row := c.db.QueryRow(ctx, query, prefs.EmployeeID, prefs.Email, prefs.BidSubmitted, prefs.BidDecided)
+	if err := scanEmailPreferences(row, prefs); err != nil {
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return nil, errors.New("error scanning row")
+	}
+	return prefs, nil
+}
+
+// emailPreferenceFlags maps events to the email_preference column that lets
+// employees opt out of them.
+var emailPreferenceFlags = map[model.EventType]string{
+	model.EventBidSubmitted: "bid_submitted",
+	model.EventBidApproved:  "bid_decided",
+	model.EventBidRejected:  "bid_decided",
+}
+
+// GetEmailRecipients returns the employee with employeeID and the
+// responsibles of organizationID (either may be empty) who have an email
+// address and did not opt out of eventType.
+func (c *postgresConnector) GetEmailRecipients(
+	ctx context.Context, eventType model.EventType, employeeID, organizationID string,
+) ([]model.EmailRecipient, error) {
+	flag, ok := emailPreferenceFlags[eventType]
+	if !ok {
+		return nil, fmt.Errorf("no email preference for event %q", eventType)
+	}
+	query := `
+	SELECT e.id, e.username, e.first_name, e.last_name, p.email
+	FROM employee AS e
+	JOIN email_preference AS p ON p.employee_id = e.id
+	WHERE p.email IS NOT NULL
+	  AND p.` + flag + `
+	  AND (e.id = $1
+	    OR e.id IN (SELECT user_id FROM organization_responsible WHERE organization_id = $2))
+	ORDER BY e.username
+	`
+	rows, err := c.db.Query(ctx, query, nullableID(employeeID), nullableID(organizationID))
+	if err != nil {
+		slog.Warn("error db query", "error", err, "query", query)
+		return nil, errors.New("error db query")
+	}
+	defer rows.Close()
+	var recipients []model.EmailRecipient
+	for rows.Next() {
+		var r model.EmailRecipient
+		if err = rows.Scan(&r.EmployeeID, &r.Username, &r.FirstName, &r.LastName, &r.Email); err != nil {
+			slog.Warn("error scan", "error", err)
+			return nil, errors.New("error scan")
+		}
+		recipients = append(recipients, r)
+	}
+	return recipients, nil
+}
+
+// SaveEmailMessages queues the messages in one transaction. A message for an
+// event and recipient that is already queued is skipped.
+func (c *postgresConnector) SaveEmailMessages(ctx context.Context, messages []model.EmailMessage) (int, error) {
+	query := `
+	INSERT INTO email_message (event_id, recipient_id, email, subject, body)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (event_id, recipient_id) DO NOTHING
+	`
+	saved := 0
+	err := pgx.BeginFunc(ctx, c.db, func(tx pgx.Tx) error {
+		for _, m := range messages {
+			tag, err := tx.Exec(ctx, query, m.EventID, m.RecipientID, m.Email, m.Subject, m.Body)
+			if err != nil {
+				slog.Warn("error db exec", "error", err, "query", query)
+				return errors.New("error db exec")
+			}
+			saved += int(tag.RowsAffected())
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return saved, nil
+}
+
+func (c *postgresConnector) GetDueEmailMessages(ctx context.Context, now time.Time, limit int) ([]model.EmailMessage, error) {
+	query := `
+	SELECT ` + emailMessageColumns + `
+	FROM email_message
+	WHERE status = 'Pending' AND next_attempt_at <= $1
+	ORDER BY next_attempt_at
+	LIMIT $2
+	`
+	rows, err := c.db.Query(ctx, query, now.UTC(), limit)
+	if err != nil {
+		slog.Warn("error db query", "error", err, "query", query)
+		return nil, errors.New("error db query")
+	}
+	defer rows.Close()
+	var messages []model.EmailMessage
+	for rows.Next() {
+		var m model.EmailMessage
+		if err = scanEmailMessage(rows, &m); err != nil {
+			slog.Warn("error scan", "error", err)
+			return nil, errors.New("error scan")
+		}
+		messages = append(messages, m)
+	}
+	return messages, nil
+}
+
+// UpdateEmailMessage stores the outcome of a send attempt.
+func (c *postgresConnector) UpdateEmailMessage(ctx context.Context, m *model.EmailMessage) error {
+	query := `
+	UPDATE email_message
+	SET status = $2, attempts = $3, next_attempt_at = $4, last_error = $5, sent_at = $6
+	WHERE id = $1
+	`
+	if _, err := c.db.Exec(ctx, query, m.ID, m.Status, m.Attempts, m.NextAttemptAt.UTC(), m.LastError, m.SentAt); err != nil {
+		slog.Warn("error db exec", "error", err, "query", query)
+		return errors.New("error db exec")
+	}
+	return nil
+}
+
+func tenderColumns(alias string) string {
+	columns := []string{"id", "name", "description", "service_type", "status", "organization_id", "creator_id", "version",
+		"created_at", "updated_at", "deadline", "publish_at", "budget_amount::text", "budget_currency", "visibility"}
+	if alias == "" {
+		return strings.Join(columns, ", ")
+	}
+	return alias + "." + strings.Join(columns, ", "+alias+".")
+}
+
+func scanTender(row pgx.Row, t *model.Tender) error {
+	var budgetAmount, budgetCurrency *string
+	err := row.Scan(&t.ID, &t.Name, &t.Description, &t.ServiceType, &t.Status, &t.OrganizationID, &t.CreatorID, &t.Version,
+		&t.CreatedAt, &t.UpdatedAt, &t.Deadline, &t.PublishAt, &budgetAmount, &budgetCurrency, &t.Visibility)
+	if err != nil {
+		return err
+	}
+	t.Budget = nil
+	if budgetAmount != nil && budgetCurrency != nil {
+		t.Budget = &model.Money{Amount: *budgetAmount, Currency: *budgetCurrency}
+	}
+	return nil
+}
+
+const auditColumns = `id, occurred_at, actor_id, actor_username, action, entity_type, entity_id, entity_version,
+	organization_id, request_id, client_ip, before, after`
+
+func scanAuditEntry(row pgx.Row, e *model.AuditEntry) error {
+	return row.Scan(&e.ID, &e.OccurredAt, &e.ActorID, &e.ActorUsername, &e.Action, &e.EntityType, &e.EntityID,
+		&e.EntityVersion, &e.OrganizationID, &e.RequestID, &e.ClientIP, &e.Before, &e.After)
+}
+
+const webhookColumns = `id, organization_id, url, event_types, secret, active, creator_id, created_at, updated_at`
+
+func scanWebhook(row pgx.Row, hook *model.Webhook) error {
+	var eventTypes []string
+	err := row.Scan(&hook.ID, &hook.OrganizationID, &hook.URL, &eventTypes, &hook.Secret, &hook.Active, &hook.CreatorID,
+		&hook.CreatedAt, &hook.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	hook.EventTypes = make([]model.EventType, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		hook.EventTypes = append(hook.EventTypes, model.EventType(eventType))
+	}
+	return nil
+}
+
+func eventTypeArgs(eventTypes []model.EventType) []string {
+	args := make([]string, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		args = append(args, string(eventType))
+	}
+	return args
+}
+
+func deliveryColumns(alias string) string {
+	columns := []string{"id", "webhook_id", "event_id", "event_type", "payload", "redelivery_of", "status", "attempts",
+		"next_attempt_at", "last_status_code", "last_error", "created_at", "delivered_at"}
+	if alias == "" {
+		return strings.Join(columns, ", ")
+	}
+	return alias + "." + strings.Join(columns, ", "+alias+".")
+}
+
+func scanDelivery(row pgx.Row, d *model.WebhookDelivery) error {
+	return row.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.RedeliveryOf, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+}
+
+const savedSearchColumns = `id, employee_id, name, service_types, keywords, budget_min::text, budget_max::text,
+	budget_currency, created_at`
+
+func scanSavedSearch(row pgx.Row, search *model.SavedSearch) error {
+	return row.Scan(&search.ID, &search.EmployeeID, &search.Name, &search.ServiceTypes, &search.Keywords,
+		&search.BudgetMin, &search.BudgetMax, &search.BudgetCurrency, &search.CreatedAt)
+}
+
+const notificationColumns = `id, employee_id, type, tender_id, tender_version, saved_search_id, title, created_at,
+	read_at`
+
+func scanNotification(row pgx.Row, n *model.Notification) error {
+	return row.Scan(&n.ID, &n.EmployeeID, &n.Type, &n.TenderID, &n.TenderVersion, &n.SavedSearchID, &n.Title,
+		&n.CreatedAt, &n.ReadAt)
+}
+
+const emailPreferenceColumns = `employee_id, email, bid_submitted, bid_decided, updated_at`
+
+func scanEmailPreferences(row pgx.Row, p *model.EmailPreferences) error {
+	return row.Scan(&p.EmployeeID, &p.Email, &p.BidSubmitted, &p.BidDecided, &p.UpdatedAt)
+}
+
+const emailMessageColumns = `id, event_id, recipient_id, email, subject, body, status, attempts, next_attempt_at,
+	last_error, created_at, sent_at`
+
+func scanEmailMessage(row pgx.Row, m *model.EmailMessage) error {
+	return row.Scan(&m.ID, &m.EventID, &m.RecipientID, &m.Email, &m.Subject, &m.Body, &m.Status, &m.Attempts,
+		&m.NextAttemptAt, &m.LastError, &m.CreatedAt, &m.SentAt)
+}
+
+func outboxColumns(alias string) string {
+	columns := []string{"id", "event_type", "aggregate_type", "aggregate_id", "aggregate_version", "payload", "created_at",
+		"status", "delivered_at"}
+	if alias == "" {
+		return strings.Join(columns, ", ")
+	}
+	return alias + "." + strings.Join(columns, ", "+alias+".")
+}
+
+func scanOutboxEvent(row pgx.Row, e *model.OutboxEvent) error {
+	return row.Scan(&e.ID, &e.Type, &e.AggregateType, &e.AggregateID, &e.AggregateVersion, &e.Payload, &e.CreatedAt,
+		&e.Status, &e.DeliveredAt)
+}
+
+func scanOutboxDelivery(row pgx.Row, d *model.OutboxDelivery) error {
+	e := &d.Event
+	return row.Scan(&e.ID, &e.Type, &e.AggregateType, &e.AggregateID, &e.AggregateVersion, &e.Payload, &e.CreatedAt,
+		&e.Status, &e.DeliveredAt, &d.Sink, &d.Attempts, &d.NextAttemptAt, &d.LastError)
+}
+
+const templateColumns = `id, organization_id, name, tender_name, tender_description, service_type, visibility,
+	budget_amount::text, budget_currency, creator_id, created_at, updated_at`
+
+func scanTemplate(row pgx.Row, tpl *model.TenderTemplate) error {
+	var budgetAmount, budgetCurrency *string
+	err := row.Scan(&tpl.ID, &tpl.OrganizationID, &tpl.Name, &tpl.TenderName, &tpl.TenderDescription, &tpl.ServiceType,
+		&tpl.Visibility, &budgetAmount, &budgetCurrency, &tpl.CreatorID, &tpl.CreatedAt, &tpl.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	tpl.Budget = nil
+	if budgetAmount != nil && budgetCurrency != nil {
+		tpl.Budget = &model.Money{Amount: *budgetAmount, Currency: *budgetCurrency}
+	}
+	return nil
+}
+
+const lotColumns = `id, tender_id, name, description, budget_amount::text, budget_currency, quantity, status,
+	awarded_bid_id, created_at, updated_at`
+
+func scanLot(row pgx.Row, lot *model.Lot) error {
+	var budgetAmount, budgetCurrency *string
+	err := row.Scan(&lot.ID, &lot.TenderID, &lot.Name, &lot.Description, &budgetAmount, &budgetCurrency, &lot.Quantity,
+		&lot.Status, &lot.AwardedBidID, &lot.CreatedAt, &lot.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	lot.Budget = nil
+	if budgetAmount != nil && budgetCurrency != nil {
+		lot.Budget = &model.Money{Amount: *budgetAmount, Currency: *budgetCurrency}
+	}
+	return nil
+}
+
+const attachmentColumns = `id, tender_id, tender_version, bid_id, bid_version, file_name, content_type, size, sha256,
+	storage_key, uploader_id, created_at`
+
+func scanAttachment(row pgx.Row, a *model.Attachment) error {
+	return row.Scan(&a.ID, &a.TenderID, &a.TenderVersion, &a.BidID, &a.BidVersion, &a.FileName, &a.ContentType, &a.Size,
+		&a.SHA256, &a.StorageKey, &a.UploaderID, &a.CreatedAt)
+}
+
+const questionColumns = `id, tender_id, author_id, text, created_at, answer, answered_by, answered_at, answer_public`
+
+func scanQuestion(row pgx.Row, q *model.Question) error {
+	return row.Scan(&q.ID, &q.TenderID, &q.AuthorID, &q.Text, &q.CreatedAt, &q.Answer, &q.AnsweredBy, &q.AnsweredAt,
+		&q.AnswerPublic)
+}
+
+const bidColumns = `id, name, description, status, author_type, author_id, tender_id, version, created_at, updated_at,
+	price_amount::text, price_currency, delivery_days, terms, lot_id`
+
+func scanBid(row pgx.Row, bid *model.Bid) error {
+	var priceAmount, priceCurrency, terms *string
+	err := row.Scan(&bid.ID, &bid.Name, &bid.Description, &bid.Status, &bid.Author, &bid.AuthorId, &bid.TenderId,
+		&bid.Version, &bid.CratedAt, &bid.UpdatedAt, &priceAmount, &priceCurrency, &bid.DeliveryDays, &terms, &bid.LotID)
+	if err != nil {
+		return err
+	}
+	bid.Price = nil
+	if priceAmount != nil && priceCurrency != nil {
+		bid.Price = &model.Money{Amount: *priceAmount, Currency: *priceCurrency}
+	}
+	bid.Terms = ""
+	if terms != nil {
+		bid.Terms = *terms
+	}
+	return nil
+}
+
+func moneyArgs(money *model.Money) (*string, *string) {
+	if money == nil {
+		return nil, nil
+	}
+	return &money.Amount, &money.Currency
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolationCode
+}
+
+func nullableID(id string) *string {
+	if id == "" {
+		return nil
+	}
+	return &id
+}
+
+func nullableText(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func NewPostgresConnector(cfg *config.Config) (DbConnector, error) {
+	pgxConfig, err := pgxpool.ParseConfig(cfg.PostgresConn)
+	if err != nil {
+		return nil, err
+	}
+	pgxConfig.MaxConns = maxConns
+	pool, err := pgxpool.NewWithConfig(context.Background(), pgxConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &postgresConnector{pool: pool, db: pool}, nil
+}
+
+func (c *postgresConnector) Ping(ctx context.Context) error {
+	return c.pool.Ping(ctx)
+}
+
+func (c *postgresConnector) PoolStats() PoolStats {
+	stat := c.pool.Stat()
+	return PoolStats{
+		AcquiredConns:        stat.AcquiredConns(),
+		IdleConns:            stat.IdleConns(),
+		TotalConns:           stat.TotalConns(),
+		MaxConns:             stat.MaxConns(),
+		AcquireCount:         stat.AcquireCount(),
+		EmptyAcquireCount:    stat.EmptyAcquireCount(),
+		CanceledAcquireCount: stat.CanceledAcquireCount(),
+		AcquireDuration:      stat.AcquireDuration(),
+	}
+}
+
+func (c *postgresConnector) GetSchemaVersion(ctx context.Context) (int, error) {
+	query := `SELECT COALESCE(MAX(version), 0) FROM schema_version`
+	var version int
+	if err := c.db.QueryRow(ctx, query).Scan(&version); err != nil {
+		slog.Warn("error scanning row", "error", err, "query", query)
+		return 0, errors.New("error scanning row")
+	}
+	return version, nil
+}
+
+// Close closes the pool. The pool waits for acquired connections to be
+// released, so Close gives up when ctx is done and leaves the rest to exit.
+func (c *postgresConnector) Close(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		c.pool.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
@@ -0,0 +1,104 @@
+package health
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// staleGrace is added to the allowed gap between beats, so that a slow tick
+// (a batch of webhooks or emails near their timeouts) is not reported as a
+// stuck worker.
+const staleGrace = 30 * time.Second
+
+// Heartbeat is embedded in background workers, which call Beat after every
+// iteration of their loop. The zero value is ready to use.
+type Heartbeat struct {
+	last atomic.Int64
+}
+
+func (h *Heartbeat) Beat() {
+	h.last.Store(time.Now().UnixNano())
+}
+
+// LastBeat returns the time of the last beat, or the zero time when the
+// worker has not completed an iteration yet.
+func (h *Heartbeat) LastBeat() time.Time {
+	last := h.last.Load()
+	if last == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, last)
+}
+
+type Beater interface {
+	LastBeat() time.Time
+}
+
+type WorkerStatus struct {
+	Name     string
+	LastBeat time.Time
+	Healthy  bool
+}
+
+type watch struct {
+	worker   Beater
+	interval time.Duration
+}
+
+// Monitor tracks background workers. A worker is unhealthy when it has not
+// beaten for three of its intervals, which means its goroutine exited or its
+// loop is stuck.
+type Monitor struct {
+	mu       sync.Mutex
+	started  time.Time
+	workers  map[string]watch
+	stopping atomic.Bool
+}
+
+func NewMonitor() *Monitor {
+	return &Monitor{
+		started: time.Now(),
+		workers: make(map[string]watch),
+	}
+}
+
+func (m *Monitor) Watch(name string, worker Beater, interval time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.workers[name] = watch{worker: worker, interval: interval}
+}
+
+// Stop marks the instance as shutting down.
+func (m *Monitor) Stop() {
+	m.stopping.Store(true)
+}
+
+func (m *Monitor) Stopping() bool {
+	return m.stopping.Load()
+}
+
+// Workers returns the status of every watched worker, ordered by name.
+func (m *Monitor) Workers(now time.Time) []WorkerStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	statuses := make([]WorkerStatus, 0, len(m.workers))
+	for name, w := range m.workers {
+		last := w.worker.LastBeat()
+		since := last
+		if since.IsZero() {
+			since = m.started
+		}
+		statuses = append(statuses, WorkerStatus{
+			Name:     name,
+			LastBeat: last,
+			Healthy:  now.Sub(since) <= 3*w.interval+staleGrace,
+		})
+	}
+	slices.SortFunc(statuses, func(a, b WorkerStatus) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return statuses
+}
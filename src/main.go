@@ -1,12 +1,26 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"zadanie-6105/chain"
 	"zadanie-6105/config"
 	"zadanie-6105/database"
+	"zadanie-6105/health"
+	"zadanie-6105/metrics"
+	"zadanie-6105/notification"
+	"zadanie-6105/outbox"
+	"zadanie-6105/scheduler"
 	"zadanie-6105/server"
+	"zadanie-6105/storage"
+	"zadanie-6105/stream"
+	"zadanie-6105/webhook"
 )
 
 func main() {
@@ -24,16 +38,159 @@ func main() {
 		os.Exit(1)
 	}
 
-	runHttpServer(cfg, dbConnector)
+	if len(os.Args) > 1 && os.Args[1] == "verify-chain" {
+		code := verifyChains(dbConnector)
+		closeDatabase(context.Background(), dbConnector)
+		os.Exit(code)
+	}
+
+	blobStorage, err := storage.NewLocalStorage(cfg.StoragePath)
+	if err != nil {
+		slog.Error("Failed to initialize blob storage", "error", err)
+		os.Exit(1)
+	}
+
+	mailer, err := notification.NewMailer(cfg)
+	if err != nil {
+		slog.Error("Failed to initialize mailer", "error", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	runWorker := func(run func(ctx context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(workersCtx)
+		}()
+	}
+	monitor := health.NewMonitor()
+	appMetrics := metrics.NewMetrics(dbConnector)
+	tenderScheduler := scheduler.NewScheduler(cfg, dbConnector)
+	monitor.Watch("scheduler", tenderScheduler, cfg.SchedulerInterval)
+	runWorker(tenderScheduler.Run)
+	dispatcher := outbox.NewDispatcher(cfg, dbConnector, outboxSinks(cfg, dbConnector, appMetrics)...)
+	monitor.Watch("outbox_dispatcher", dispatcher, cfg.OutboxDispatchInterval)
+	runWorker(dispatcher.Run)
+	webhookWorker := webhook.NewWorker(cfg, dbConnector)
+	monitor.Watch("webhook_worker", webhookWorker, cfg.WebhookDeliveryInterval)
+	runWorker(webhookWorker.Run)
+	emailWorker := notification.NewEmailWorker(cfg, dbConnector, mailer)
+	monitor.Watch("email_worker", emailWorker, cfg.MailSendInterval)
+	runWorker(emailWorker.Run)
+	hub := stream.NewHub(cfg, dbConnector)
+	monitor.Watch("stream_hub", hub, cfg.StreamPollInterval)
+	runWorker(hub.Run)
+
+	httpServer := newHttpServer(cfg, dbConnector, blobStorage, hub, monitor, appMetrics)
+	// Closing the hub ends the event streams that Shutdown would otherwise wait for.
+	httpServer.RegisterOnShutdown(hub.Close)
+	listenErr := make(chan error, 1)
+	go func() {
+		slog.Debug("start http server", "address", cfg.ServerAddress)
+		listenErr <- httpServer.ListenAndServe()
+	}()
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutting down")
+	case err = <-listenErr:
+		slog.Error("error during listen", "error", err)
+	}
+
+	monitor.Stop()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	// In-flight requests finish while the workers and the pool are still up.
+	shutdownHttpServer(shutdownCtx, httpServer)
+	stopWorkers()
+	waitWorkers(shutdownCtx, &workers)
+	closeDatabase(shutdownCtx, dbConnector)
+	if err != nil {
+		os.Exit(1)
+	}
+	slog.Info("Service stopped")
 }
 
-func runHttpServer(cfg *config.Config, dbConnector database.DbConnector) {
-	srv := server.NewServer(cfg, dbConnector)
-	slog.Debug("start http server")
-	router := srv.Router()
-	http.Handle("/", router)
-	if err := http.ListenAndServe(cfg.ServerAddress, nil); err != nil {
-		slog.Warn("error during listen", "error", err)
+// verifyChains prints a line per hash chain and returns a non-zero exit code
+// when any chain is broken or cannot be read.
+func verifyChains(dbConnector database.DbConnector) int {
+	reports, err := chain.VerifyAll(context.Background(), dbConnector)
+	if err != nil {
+		slog.Error("Failed to verify hash chains", "error", err)
+		return 2
+	}
+	code := 0
+	for _, report := range reports {
+		if report.Valid() {
+			fmt.Printf("%s: ok, %d links checked\n", report.Chain, report.Checked)
+			continue
+		}
+		code = 1
+		fmt.Printf("%s: broken at %s (seq %d) after %d valid links: %s\n",
+			report.Chain, report.Broken.ID, report.Broken.Seq, report.Checked, report.Broken.Reason)
+	}
+	return code
+}
+
+// outboxSinks lists the dispatcher sinks. Deliveries are tracked per sink
+// name, so a failing HTTP sink does not hold back the others.
+func outboxSinks(cfg *config.Config, dbConnector database.DbConnector, appMetrics *metrics.Metrics) []outbox.Sink {
+	sinks := []outbox.Sink{
+		outbox.NewLogSink(),
+		webhook.NewSink(dbConnector),
+		notification.NewAlertSink(dbConnector),
+		notification.NewEmailSink(dbConnector),
+	}
+	if cfg.OutboxHTTPSinkURL != "" {
+		sinks = append(sinks, outbox.NewHTTPSink(cfg.OutboxHTTPSinkURL, cfg.OutboxHTTPSinkTimeout))
+	}
+	return append(sinks, appMetrics.Sink())
+}
+
+func newHttpServer(
+	cfg *config.Config, dbConnector database.DbConnector, blobStorage storage.BlobStorage, hub *stream.Hub,
+	monitor *health.Monitor, appMetrics *metrics.Metrics,
+) *http.Server {
+	srv := server.NewServer(cfg, dbConnector, blobStorage, hub, monitor, appMetrics)
+	return &http.Server{
+		Addr:              cfg.ServerAddress,
+		Handler:           srv.Router(),
+		ReadHeaderTimeout: cfg.ServerReadHeaderTimeout,
+		ReadTimeout:       cfg.ServerReadTimeout,
+		WriteTimeout:      cfg.ServerWriteTimeout,
+		IdleTimeout:       cfg.ServerIdleTimeout,
+	}
+}
+
+// shutdownHttpServer stops accepting connections and waits for in-flight
+// requests; connections still open at the deadline are closed.
+func shutdownHttpServer(ctx context.Context, httpServer *http.Server) {
+	if err := httpServer.Shutdown(ctx); err != nil {
+		slog.Warn("error during http server shutdown", "error", err)
+		_ = httpServer.Close()
 	}
 	slog.Debug("http server stopped")
 }
+
+func waitWorkers(ctx context.Context, workers *sync.WaitGroup) {
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		slog.Debug("background workers stopped")
+	case <-ctx.Done():
+		slog.Warn("background workers did not stop in time")
+	}
+}
+
+func closeDatabase(ctx context.Context, dbConnector database.DbConnector) {
+	if err := dbConnector.Close(ctx); err != nil {
+		slog.Warn("error closing database connector", "error", err)
+	}
+}
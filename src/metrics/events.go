@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"zadanie-6105/model"
+)
+
+type eventCounter struct {
+	vec   *prometheus.CounterVec
+	label string
+}
+
+// newEventCounters maps the counted domain events to their counters. Label
+// values are created up front so that the series are exported as zero.
+func newEventCounters() (*prometheus.CounterVec, *prometheus.CounterVec, map[model.EventType]eventCounter) {
+	tenders := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tenders_total",
+		Help:      "Tender lifecycle events by event.",
+	}, []string{"event"})
+	bids := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bids_total",
+		Help:      "Bid lifecycle events by event.",
+	}, []string{"event"})
+	counters := map[model.EventType]eventCounter{
+		model.EventTenderCreated:   {vec: tenders, label: "created"},
+		model.EventTenderPublished: {vec: tenders, label: "published"},
+		model.EventTenderClosed:    {vec: tenders, label: "closed"},
+		model.EventBidSubmitted:    {vec: bids, label: "submitted"},
+		model.EventBidApproved:     {vec: bids, label: "approved"},
+		model.EventBidRejected:     {vec: bids, label: "rejected"},
+	}
+	for _, counter := range counters {
+		counter.vec.WithLabelValues(counter.label)
+	}
+	return tenders, bids, counters
+}
+
+// Sink counts domain events as the outbox dispatcher delivers them, so tenders
+// published or closed by the scheduler are counted as well as those changed
+// through the API. The counters lag the writes by up to one dispatch
+// interval. Deliveries are tracked per sink and this sink never fails, so
+// every event is counted once, even when other sinks retry or dead-letter it.
+type Sink struct {
+	counters map[model.EventType]eventCounter
+}
+
+func (m *Metrics) Sink() *Sink {
+	return &Sink{counters: m.counters}
+}
+
+func (s *Sink) Name() string {
+	return "metrics"
+}
+
+func (s *Sink) Deliver(_ context.Context, event *model.OutboxEvent) error {
+	if counter, ok := s.counters[event.Type]; ok {
+		counter.vec.WithLabelValues(counter.label).Inc()
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddlewareLabelsRouteTemplate(t *testing.T) {
+	m := NewMetrics(nil)
+	router := mux.NewRouter()
+	router.Use(m.Middleware)
+	router.HandleFunc("/api/tenders/{tenderId}/edit", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}).Methods(http.MethodPatch)
+
+	for _, id := range []string{"a0000000-0000-0000-0000-000000000001", "a0000000-0000-0000-0000-000000000002"} {
+		req := httptest.NewRequest(http.MethodPatch, "/api/tenders/"+id+"/edit", nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	got := testutil.ToFloat64(m.requests.WithLabelValues("/api/tenders/{tenderId}/edit", http.MethodPatch, "404"))
+	if got != 2 {
+		t.Errorf("requests for the route template = %v, want 2", got)
+	}
+	if series := testutil.CollectAndCount(m.requests); series != 1 {
+		t.Errorf("request counter has %d series, want 1 (ids must not become labels)", series)
+	}
+}
+
+func TestStatusRecorderDefaultsToOK(t *testing.T) {
+	recorder := &statusRecorder{ResponseWriter: httptest.NewRecorder()}
+	_, _ = recorder.Write([]byte("ok"))
+	recorder.WriteHeader(http.StatusInternalServerError)
+	if recorder.Status() != http.StatusOK {
+		t.Errorf("Status() = %d, want the implicit 200 sent with the body", recorder.Status())
+	}
+}
@@ -0,0 +1,197 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventTenderCreated   EventType = "tender.created"
+	EventTenderEdited    EventType = "tender.edited"
+	EventTenderPublished EventType = "tender.published"
+	EventTenderClosed    EventType = "tender.closed"
+	EventBidSubmitted    EventType = "bid.submitted"
+	EventBidApproved     EventType = "bid.approved"
+	EventBidRejected     EventType = "bid.rejected"
+)
+
+type OutboxStatus string
+
+const (
+	OutboxPending    OutboxStatus = "Pending"
+	OutboxDelivered  OutboxStatus = "Delivered"
+	OutboxDeadLetter OutboxStatus = "DeadLetter"
+)
+
+// OutboxEvent is a domain event stored in the same transaction as the change
+// that caused it and delivered to sinks later.
+type OutboxEvent struct {
+	ID               int64
+	Type             EventType
+	AggregateType    string
+	AggregateID      string
+	AggregateVersion int
+	Payload          []byte
+	CreatedAt        time.Time
+	Status           OutboxStatus
+	DeliveredAt      *time.Time
+}
+
+// OutboxDelivery is the delivery state of an event for one sink. Attempts is
+// zero and NextAttemptAt is the event's creation time until the first attempt.
+type OutboxDelivery struct {
+	Event         OutboxEvent
+	Sink          string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     *string
+}
+
+// TenderEventPayload is the public shape of tender events. Consumers depend
+// on it, so fields may be added but not renamed or removed.
+type TenderEventPayload struct {
+	ID             string     `json:"id"`
+	Name           string     `json:"name"`
+	Description    string     `json:"description"`
+	Status         string     `json:"status"`
+	ServiceType    string     `json:"serviceType"`
+	OrganizationID string     `json:"organizationId"`
+	Visibility     string     `json:"visibility"`
+	Version        int        `json:"version"`
+	Deadline       *time.Time `json:"deadline,omitempty"`
+	PublishAt      *time.Time `json:"publishAt,omitempty"`
+	BudgetAmount   *string    `json:"budgetAmount,omitempty"`
+	BudgetCurrency *string    `json:"budgetCurrency,omitempty"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+func NewTenderEvent(eventType EventType, t *Tender) (*OutboxEvent, error) {
+	payload := TenderEventPayload{
+		ID:             t.ID,
+		Name:           t.Name,
+		Description:    t.Description,
+		Status:         string(t.Status),
+		ServiceType:    t.ServiceType,
+		OrganizationID: t.OrganizationID,
+		Visibility:     string(t.Visibility),
+		Version:        t.Version,
+		Deadline:       t.Deadline,
+		PublishAt:      t.PublishAt,
+		UpdatedAt:      t.UpdatedAt,
+	}
+	if t.Budget != nil {
+		payload.BudgetAmount = &t.Budget.Amount
+		payload.BudgetCurrency = &t.Budget.Currency
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &OutboxEvent{
+		Type:             eventType,
+		AggregateType:    EntityTender,
+		AggregateID:      t.ID,
+		AggregateVersion: t.Version,
+		Payload:          data,
+	}, nil
+}
+
+// BidEventPayload is the public shape of bid events. Tender fields are
+// copied so consumers do not have to load the tender.
+type BidEventPayload struct {
+	ID                   string    `json:"id"`
+	Name                 string    `json:"name"`
+	Status               string    `json:"status"`
+	AuthorType           string    `json:"authorType"`
+	AuthorID             string    `json:"authorId"`
+	Version              int       `json:"version"`
+	LotID                *string   `json:"lotId,omitempty"`
+	TenderID             string    `json:"tenderId"`
+	TenderName           string    `json:"tenderName"`
+	TenderOrganizationID string    `json:"tenderOrganizationId"`
+	UpdatedAt            time.Time `json:"updatedAt"`
+}
+
+func NewBidEvent(eventType EventType, bid *Bid, tender *Tender) (*OutboxEvent, error) {
+	payload := BidEventPayload{
+		ID:                   bid.ID,
+		Name:                 bid.Name,
+		Status:               string(bid.Status),
+		AuthorType:           string(bid.Author),
+		AuthorID:             bid.AuthorId,
+		Version:              bid.Version,
+		LotID:                bid.LotID,
+		TenderID:             tender.ID,
+		TenderName:           tender.Name,
+		TenderOrganizationID: tender.OrganizationID,
+		UpdatedAt:            bid.UpdatedAt,
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &OutboxEvent{
+		Type:             eventType,
+		AggregateType:    EntityBid,
+		AggregateID:      bid.ID,
+		AggregateVersion: bid.Version,
+		Payload:          data,
+	}, nil
+}
+
+// TenderStatusEvent returns the event emitted when a tender moves to status.
+func TenderStatusEvent(status TenderStatus) EventType {
+	switch status {
+	case TenderPublished:
+		return EventTenderPublished
+	case TenderClosed:
+		return EventTenderClosed
+	default:
+		return EventTenderEdited
+	}
+}
+
+// EventTypes lists the events organizations can subscribe webhooks to.
+var EventTypes = []EventType{EventTenderCreated, EventTenderEdited, EventTenderPublished, EventTenderClosed}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "Pending"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "Delivered"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "Failed"
+)
+
+// Webhook is an organization subscription to domain events. Secret signs the
+// delivered payloads and is kept out of JSON snapshots such as audit entries.
+type Webhook struct {
+	ID             string
+	OrganizationID string
+	URL            string
+	EventTypes     []EventType
+	Secret         string `json:"-"`
+	Active         bool
+	CreatorID      string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// WebhookDelivery is one attempt series of sending an event to a webhook.
+// Manual redeliveries are stored as new rows pointing at the original one.
+type WebhookDelivery struct {
+	ID             string
+	WebhookID      string
+	EventID        int64
+	EventType      EventType
+	Payload        []byte
+	RedeliveryOf   *string
+	Status         WebhookDeliveryStatus
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode *int
+	LastError      *string
+	CreatedAt      time.Time
+	DeliveredAt    *time.Time
+}
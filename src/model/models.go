@@ -20,6 +20,13 @@ const (
 	TenderClosed    TenderStatus = "Closed"
 )
 
+type TenderVisibility string
+
+const (
+	TenderPublic     TenderVisibility = "Public"
+	TenderInviteOnly TenderVisibility = "InviteOnly"
+)
+
 type BidStatus string
 
 const (
@@ -28,6 +35,21 @@ const (
 	BidCanceled  BidStatus = "Canceled"
 )
 
+type BidDecision string
+
+const (
+	BidApproved BidDecision = "Approved"
+	BidRejected BidDecision = "Rejected"
+)
+
+type LotStatus string
+
+const (
+	LotOpen      LotStatus = "Open"
+	LotAwarded   LotStatus = "Awarded"
+	LotCancelled LotStatus = "Cancelled"
+)
+
 type AuthorType string
 
 const (
@@ -59,6 +81,19 @@ type Employee struct {
 	UpdatedAt time.Time
 }
 
+type ServiceType struct {
+	Name       string
+	Parent     *string
+	Deprecated bool
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type Money struct {
+	Amount   string
+	Currency string
+}
+
 type Tender struct {
 	ID             string
 	Name           string
@@ -70,16 +105,244 @@ type Tender struct {
 	CreatorID      string
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
+	Deadline       *time.Time
+	PublishAt      *time.Time
+	Budget         *Money
+	Visibility     TenderVisibility
+}
+
+type TenderTemplate struct {
+	ID                string
+	OrganizationID    string
+	Name              string
+	TenderName        string
+	TenderDescription string
+	ServiceType       string
+	Visibility        TenderVisibility
+	Budget            *Money
+	CreatorID         string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+type TenderInvitation struct {
+	TenderID       string
+	OrganizationID string
+	InvitedBy      string
+	CreatedAt      time.Time
 }
 
 type Bid struct {
-	ID        string
-	Name      string
-	Status    BidStatus
-	Author    AuthorType
-	AuthorId  string
-	TenderId  string
-	Version   int
-	CratedAt  time.Time
-	UpdatedAt time.Time
+	ID           string
+	Name         string
+	Description  string
+	Status       BidStatus
+	Author       AuthorType
+	AuthorId     string
+	TenderId     string
+	Version      int
+	CratedAt     time.Time
+	UpdatedAt    time.Time
+	Price        *Money
+	DeliveryDays *int
+	Terms        string
+	LotID        *string
+}
+
+type Lot struct {
+	ID           string
+	TenderID     string
+	Name         string
+	Description  string
+	Budget       *Money
+	Quantity     int
+	Status       LotStatus
+	AwardedBidID *string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type Attachment struct {
+	ID            string
+	TenderID      string
+	TenderVersion int
+	BidID         *string
+	BidVersion    *int
+	FileName      string
+	ContentType   string
+	Size          int64
+	SHA256        string
+	StorageKey    string
+	UploaderID    string
+	CreatedAt     time.Time
+}
+
+type Question struct {
+	ID           string
+	TenderID     string
+	AuthorID     string
+	Text         string
+	CreatedAt    time.Time
+	Answer       *string
+	AnsweredBy   *string
+	AnsweredAt   *time.Time
+	AnswerPublic bool
+}
+
+// SavedSearch is an employee's standing tender filter. Empty ServiceTypes and
+// nil fields match any tender; a service type also matches its descendants.
+type SavedSearch struct {
+	ID             string
+	EmployeeID     string
+	Name           string
+	ServiceTypes   []string
+	Keywords       *string
+	BudgetMin      *string
+	BudgetMax      *string
+	BudgetCurrency *string
+	CreatedAt      time.Time
+}
+
+type NotificationType string
+
+const (
+	NotificationTenderMatched NotificationType = "tender.matched"
+)
+
+type Notification struct {
+	ID            string
+	EmployeeID    string
+	Type          NotificationType
+	TenderID      string
+	TenderVersion int
+	SavedSearchID *string
+	Title         string
+	CreatedAt     time.Time
+	ReadAt        *time.Time
+}
+
+// EmailStatus is the delivery state of a queued email.
+type EmailStatus string
+
+const (
+	EmailPending EmailStatus = "Pending"
+	EmailSent    EmailStatus = "Sent"
+	EmailFailed  EmailStatus = "Failed"
+)
+
+// EmailRecipient is an employee with a notification address.
+type EmailRecipient struct {
+	EmployeeID string
+	Username   string
+	FirstName  *string
+	LastName   *string
+	Email      string
+}
+
+// EmailPreferences holds an employee's email address and which notification
+// emails they receive. Employees without an address get no emails.
+type EmailPreferences struct {
+	EmployeeID   string
+	Email        *string
+	BidSubmitted bool
+	BidDecided   bool
+	UpdatedAt    time.Time
+}
+
+type EmailMessage struct {
+	ID            int64
+	EventID       int64
+	RecipientID   string
+	Email         string
+	Subject       string
+	Body          string
+	Status        EmailStatus
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     *string
+	CreatedAt     time.Time
+	SentAt        *time.Time
+}
+
+const (
+	AuditTenderCreate             = "tender.create"
+	AuditTenderCreateFromTemplate = "tender.create_from_template"
+	AuditTenderClone              = "tender.clone"
+	AuditTenderEdit               = "tender.edit"
+	AuditTenderStatus             = "tender.status"
+	AuditTenderRollback           = "tender.rollback"
+	AuditTenderScheduleCancel     = "tender.schedule_cancel"
+	AuditTenderPublishScheduled   = "tender.publish_scheduled"
+	AuditTenderCloseExpired       = "tender.close_expired"
+	AuditTenderCloseLots          = "tender.close_lots"
+	AuditTenderCloseAward         = "tender.close_award"
+	AuditAttachmentUpload         = "attachment.upload"
+	AuditQuestionCreate           = "question.create"
+	AuditQuestionAnswer           = "question.answer"
+	AuditInvitationCreate         = "invitation.create"
+	AuditInvitationDelete         = "invitation.delete"
+	AuditLotCreate                = "lot.create"
+	AuditLotEdit                  = "lot.edit"
+	AuditLotStatus                = "lot.status"
+	AuditTemplateCreate           = "template.create"
+	AuditTemplateEdit             = "template.edit"
+	AuditTemplateDelete           = "template.delete"
+	AuditServiceTypeCreate        = "service_type.create"
+	AuditServiceTypeEdit          = "service_type.edit"
+	AuditWebhookCreate            = "webhook.create"
+	AuditWebhookEdit              = "webhook.edit"
+	AuditWebhookDelete            = "webhook.delete"
+	AuditWebhookRedeliver         = "webhook.redeliver"
+	AuditSavedSearchCreate        = "saved_search.create"
+	AuditSavedSearchDelete        = "saved_search.delete"
+	AuditBidCreate                = "bid.create"
+	AuditBidStatus                = "bid.status"
+	AuditBidDecision              = "bid.decision"
+	AuditNotificationRead         = "notification.read"
+	AuditNotificationReadAll      = "notification.read_all"
+	AuditEmailPreferencesEdit     = "email_preferences.edit"
+)
+
+const (
+	EntityTender           = "tender"
+	EntityAttachment       = "attachment"
+	EntityQuestion         = "question"
+	EntityInvitation       = "invitation"
+	EntityLot              = "lot"
+	EntityTemplate         = "template"
+	EntityServiceType      = "service_type"
+	EntityWebhook          = "webhook"
+	EntitySavedSearch      = "saved_search"
+	EntityBid              = "bid"
+	EntityNotification     = "notification"
+	EntityEmailPreferences = "email_preferences"
+)
+
+// AuditEntry records one mutation. Before and After hold JSON snapshots of the
+// target entity; ActorID is nil for changes made by background jobs.
+type AuditEntry struct {
+	ID             int64
+	OccurredAt     time.Time
+	ActorID        *string
+	ActorUsername  *string
+	Action         string
+	EntityType     string
+	EntityID       string
+	EntityVersion  *int
+	OrganizationID *string
+	RequestID      *string
+	ClientIP       *string
+	Before         []byte
+	After          []byte
+}
+
+// ChainLink is one row of a hash chain. Rows with the same Group form one chain
+// ordered by Seq; Content is the canonical text the row hash was computed from.
+type ChainLink struct {
+	Group    string
+	ID       string
+	Seq      int64
+	PrevHash *string
+	Hash     string
+	Content  string
 }
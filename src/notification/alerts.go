@@ -0,0 +1,36 @@
+package notification
+
+import (
+	"context"
+	"log/slog"
+	"zadanie-6105/database"
+	"zadanie-6105/model"
+)
+
+// AlertSink matches tenders against saved searches when they are published
+// and creates in-app notifications for the matching employees.
+type AlertSink struct {
+	db database.DbConnector
+}
+
+func NewAlertSink(db database.DbConnector) *AlertSink {
+	return &AlertSink{db: db}
+}
+
+func (s *AlertSink) Name() string {
+	return "alerts"
+}
+
+func (s *AlertSink) Deliver(ctx context.Context, event *model.OutboxEvent) error {
+	if event.Type != model.EventTenderPublished {
+		return nil
+	}
+	created, err := s.db.SaveTenderMatchNotifications(ctx, event.AggregateID, event.AggregateVersion)
+	if err != nil {
+		return err
+	}
+	if created > 0 {
+		slog.Debug("tender match notifications created", "tender_id", event.AggregateID, "count", created)
+	}
+	return nil
+}
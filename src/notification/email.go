@@ -0,0 +1,204 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"github.com/samber/lo"
+	"log/slog"
+	"strings"
+	"text/template"
+	"time"
+	"zadanie-6105/config"
+	"zadanie-6105/database"
+	"zadanie-6105/health"
+	"zadanie-6105/model"
+	"zadanie-6105/outbox"
+)
+
+const (
+	emailBatchSize = 50
+	emailLockName  = "email_worker"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// emailTemplates holds one template per event. Each file defines a "subject"
+// and a "body" template rendered with emailData.
+var emailTemplates = map[model.EventType]*template.Template{
+	model.EventBidSubmitted: parseTemplate(model.EventBidSubmitted),
+	model.EventBidApproved:  parseTemplate(model.EventBidApproved),
+	model.EventBidRejected:  parseTemplate(model.EventBidRejected),
+}
+
+func parseTemplate(eventType model.EventType) *template.Template {
+	return template.Must(template.ParseFS(templateFS, "templates/"+string(eventType)+".tmpl"))
+}
+
+type emailData struct {
+	Recipient model.EmailRecipient
+	Bid       model.BidEventPayload
+}
+
+// RenderEmail renders the subject and body of the email sent for an event.
+func RenderEmail(eventType model.EventType, recipient model.EmailRecipient, bid model.BidEventPayload) (string, string, error) {
+	tmpl, ok := emailTemplates[eventType]
+	if !ok {
+		return "", "", fmt.Errorf("no email template for event %q", eventType)
+	}
+	data := emailData{Recipient: recipient, Bid: bid}
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return "", "", err
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(subject.String()), strings.TrimLeft(body.String(), "\n"), nil
+}
+
+// EmailSink turns bid events from the outbox into queued emails: submitted
+// bids go to the tender organization's responsibles, decisions go to the bid
+// author. Sending happens later in EmailWorker.
+type EmailSink struct {
+	db database.DbConnector
+}
+
+func NewEmailSink(db database.DbConnector) *EmailSink {
+	return &EmailSink{db: db}
+}
+
+func (s *EmailSink) Name() string {
+	return "email"
+}
+
+func (s *EmailSink) Deliver(ctx context.Context, event *model.OutboxEvent) error {
+	if _, ok := emailTemplates[event.Type]; !ok {
+		return nil
+	}
+	var bid model.BidEventPayload
+	if err := json.Unmarshal(event.Payload, &bid); err != nil {
+		slog.Warn("error decoding bid event", "error", err, "id", event.ID)
+		return nil
+	}
+	employeeID, organizationID := "", bid.TenderOrganizationID
+	if event.Type != model.EventBidSubmitted {
+		organizationID = ""
+		if model.AuthorType(bid.AuthorType) == model.AuthorUser {
+			employeeID = bid.AuthorID
+		} else {
+			organizationID = bid.AuthorID
+		}
+	}
+	recipients, err := s.db.GetEmailRecipients(ctx, event.Type, employeeID, organizationID)
+	if err != nil {
+		return err
+	}
+	messages := make([]model.EmailMessage, 0, len(recipients))
+	for _, recipient := range recipients {
+		subject, body, err := RenderEmail(event.Type, recipient, bid)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, model.EmailMessage{
+			EventID:     event.ID,
+			RecipientID: recipient.EmployeeID,
+			Email:       recipient.Email,
+			Subject:     subject,
+			Body:        body,
+		})
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	queued, err := s.db.SaveEmailMessages(ctx, messages)
+	if err != nil {
+		return err
+	}
+	slog.Debug("emails queued", "event_id", event.ID, "count", queued)
+	return nil
+}
+
+// EmailWorker sends queued emails and retries failed ones with exponential
+// backoff until maxAttempts is reached.
+type EmailWorker struct {
+	health.Heartbeat
+	db          database.DbConnector
+	mailer      Mailer
+	interval    time.Duration
+	maxAttempts int
+	backoff     time.Duration
+}
+
+func NewEmailWorker(cfg *config.Config, db database.DbConnector, mailer Mailer) *EmailWorker {
+	return &EmailWorker{
+		db:          db,
+		mailer:      mailer,
+		interval:    cfg.MailSendInterval,
+		maxAttempts: cfg.MailMaxAttempts,
+		backoff:     cfg.MailRetryBackoff,
+	}
+}
+
+func (w *EmailWorker) Run(ctx context.Context) {
+	slog.Debug("start email worker", "interval", w.interval)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.tick(ctx)
+		w.Beat()
+		select {
+		case <-ctx.Done():
+			slog.Debug("email worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *EmailWorker) tick(ctx context.Context) {
+	_, err := w.db.RunExclusive(ctx, emailLockName, func(ctx context.Context) error {
+		messages, err := w.db.GetDueEmailMessages(ctx, time.Now(), emailBatchSize)
+		if err != nil {
+			return err
+		}
+		for i := range messages {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err = w.send(ctx, &messages[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		slog.Warn("error sending emails", "error", err)
+	}
+}
+
+func (w *EmailWorker) send(ctx context.Context, m *model.EmailMessage) error {
+	err := w.mailer.Send(ctx, &Message{To: m.Email, Subject: m.Subject, Body: m.Body})
+	if err != nil && ctx.Err() != nil {
+		return nil
+	}
+	now := time.Now()
+	m.Attempts++
+	switch {
+	case err == nil:
+		m.Status = model.EmailSent
+		m.SentAt = &now
+		m.LastError = nil
+	case m.Attempts >= w.maxAttempts:
+		slog.Warn("email sending failed", "id", m.ID, "attempts", m.Attempts, "error", err)
+		m.Status = model.EmailFailed
+		m.LastError = lo.ToPtr(err.Error())
+	default:
+		m.NextAttemptAt = now.Add(outbox.RetryDelay(w.backoff, m.Attempts))
+		m.LastError = lo.ToPtr(err.Error())
+	}
+	return w.db.UpdateEmailMessage(ctx, m)
+}
@@ -0,0 +1,119 @@
+package notification
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"zadanie-6105/database"
+	"zadanie-6105/model"
+)
+
+type recipientQuery struct {
+	eventType      model.EventType
+	employeeID     string
+	organizationID string
+}
+
+type fakeEmailDb struct {
+	database.DbConnector
+	recipients []model.EmailRecipient
+	queries    []recipientQuery
+	saved      []model.EmailMessage
+}
+
+func (db *fakeEmailDb) GetEmailRecipients(
+	_ context.Context, eventType model.EventType, employeeID, organizationID string,
+) ([]model.EmailRecipient, error) {
+	db.queries = append(db.queries, recipientQuery{eventType, employeeID, organizationID})
+	return db.recipients, nil
+}
+
+func (db *fakeEmailDb) SaveEmailMessages(_ context.Context, messages []model.EmailMessage) (int, error) {
+	db.saved = append(db.saved, messages...)
+	return len(messages), nil
+}
+
+func testBidEvent(t *testing.T, eventType model.EventType) *model.OutboxEvent {
+	t.Helper()
+	bid := &model.Bid{
+		ID:       "b0000000-0000-0000-0000-000000000001",
+		Name:     "Road repair offer",
+		Status:   model.BidPublished,
+		Author:   model.AuthorUser,
+		AuthorId: "e0000000-0000-0000-0000-000000000001",
+		TenderId: "t0000000-0000-0000-0000-000000000001",
+		Version:  2,
+	}
+	tender := &model.Tender{
+		ID:             bid.TenderId,
+		Name:           "Roadworks 2026",
+		OrganizationID: "o0000000-0000-0000-0000-000000000001",
+	}
+	event, err := model.NewBidEvent(eventType, bid, tender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event.ID = 42
+	return event
+}
+
+func TestEmailSinkQueuesSubmittedBidForResponsibles(t *testing.T) {
+	db := &fakeEmailDb{recipients: []model.EmailRecipient{{
+		EmployeeID: "e0000000-0000-0000-0000-000000000002",
+		Username:   "responsible",
+		Email:      "responsible@example.com",
+	}}}
+	event := testBidEvent(t, model.EventBidSubmitted)
+
+	if err := NewEmailSink(db).Deliver(context.Background(), event); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+
+	want := recipientQuery{model.EventBidSubmitted, "", "o0000000-0000-0000-0000-000000000001"}
+	if len(db.queries) != 1 || db.queries[0] != want {
+		t.Fatalf("recipient queries = %+v, want [%+v]", db.queries, want)
+	}
+	if len(db.saved) != 1 {
+		t.Fatalf("queued %d emails, want 1", len(db.saved))
+	}
+	msg := db.saved[0]
+	if msg.EventID != 42 || msg.RecipientID != "e0000000-0000-0000-0000-000000000002" ||
+		msg.Email != "responsible@example.com" {
+		t.Errorf("queued email = %+v", msg)
+	}
+	if !strings.Contains(msg.Subject, "Roadworks 2026") {
+		t.Errorf("subject %q does not name the tender", msg.Subject)
+	}
+	if !strings.Contains(msg.Body, "Road repair offer") || !strings.Contains(msg.Body, "responsible") {
+		t.Errorf("body does not name the bid or the recipient:\n%s", msg.Body)
+	}
+}
+
+func TestEmailSinkSendsDecisionToAuthor(t *testing.T) {
+	db := &fakeEmailDb{}
+	event := testBidEvent(t, model.EventBidApproved)
+
+	if err := NewEmailSink(db).Deliver(context.Background(), event); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+
+	want := recipientQuery{model.EventBidApproved, "e0000000-0000-0000-0000-000000000001", ""}
+	if len(db.queries) != 1 || db.queries[0] != want {
+		t.Fatalf("recipient queries = %+v, want [%+v]", db.queries, want)
+	}
+	if len(db.saved) != 0 {
+		t.Errorf("queued %d emails without recipients", len(db.saved))
+	}
+}
+
+func TestEmailSinkIgnoresTenderEvents(t *testing.T) {
+	db := &fakeEmailDb{}
+	event := &model.OutboxEvent{ID: 1, Type: model.EventTenderCreated, AggregateType: model.EntityTender}
+
+	if err := NewEmailSink(db).Deliver(context.Background(), event); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+	if len(db.queries) != 0 {
+		t.Errorf("tender event queried recipients: %+v", db.queries)
+	}
+}
@@ -0,0 +1,134 @@
+package outbox
+
+import (
+	"context"
+	"github.com/samber/lo"
+	"log/slog"
+	"time"
+	"zadanie-6105/config"
+	"zadanie-6105/database"
+	"zadanie-6105/health"
+)
+
+const (
+	dispatchBatchSize = 100
+	maxRetryBackoff   = time.Hour
+	dispatchLockName  = "outbox_dispatcher"
+)
+
+// Dispatcher delivers outbox events to its sinks. Every sink gets the events
+// strictly in the order they were stored, but sinks progress independently: an
+// event a sink failed to deliver blocks only that sink's later events, until
+// the sink delivers it or gives up on it after maxAttempts attempts.
+type Dispatcher struct {
+	health.Heartbeat
+	db          database.DbConnector
+	sinks       []Sink
+	sinkNames   []string
+	interval    time.Duration
+	maxAttempts int
+	backoff     time.Duration
+}
+
+func NewDispatcher(cfg *config.Config, db database.DbConnector, sinks ...Sink) *Dispatcher {
+	return &Dispatcher{
+		db:          db,
+		sinks:       sinks,
+		sinkNames:   lo.Map(sinks, func(sink Sink, _ int) string { return sink.Name() }),
+		interval:    cfg.OutboxDispatchInterval,
+		maxAttempts: cfg.OutboxMaxAttempts,
+		backoff:     cfg.OutboxRetryBackoff,
+	}
+}
+
+func (d *Dispatcher) Run(ctx context.Context) {
+	slog.Debug("start outbox dispatcher", "interval", d.interval, "sinks", d.sinkNames)
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		d.tick(ctx)
+		d.Beat()
+		select {
+		case <-ctx.Done():
+			slog.Debug("outbox dispatcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick drains the outbox for every sink while holding a database lock, so
+// that several service instances never deliver events concurrently or out of
+// order.
+func (d *Dispatcher) tick(ctx context.Context) {
+	_, err := d.db.RunExclusive(ctx, dispatchLockName, func(ctx context.Context) error {
+		for _, sink := range d.sinks {
+			for {
+				done, err := d.dispatchBatch(ctx, sink)
+				if err != nil {
+					return err
+				}
+				if done {
+					break
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		slog.Warn("error dispatching outbox events", "error", err)
+	}
+}
+
+// dispatchBatch delivers one batch of pending events to sink. It reports done
+// when there is nothing more to deliver to the sink right now.
+func (d *Dispatcher) dispatchBatch(ctx context.Context, sink Sink) (bool, error) {
+	deliveries, err := d.db.GetPendingOutboxDeliveries(ctx, sink.Name(), dispatchBatchSize)
+	if err != nil {
+		return true, err
+	}
+	now := time.Now()
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		event := &delivery.Event
+		if delivery.NextAttemptAt.After(now) {
+			return true, nil
+		}
+		if err = sink.Deliver(ctx, event); err == nil {
+			if err = d.db.MarkOutboxDelivered(ctx, event.ID, sink.Name(), d.sinkNames); err != nil {
+				return true, err
+			}
+			continue
+		}
+		if ctx.Err() != nil {
+			return true, nil
+		}
+		attempts := delivery.Attempts + 1
+		if attempts >= d.maxAttempts {
+			slog.Error("outbox event moved to dead letter", "id", event.ID, "type", event.Type, "sink", sink.Name(),
+				"attempts", attempts, "error", err)
+			if err = d.db.DeadLetterOutboxDelivery(ctx, event.ID, sink.Name(), err.Error(), d.sinkNames); err != nil {
+				return true, err
+			}
+			continue
+		}
+		slog.Warn("error delivering outbox event", "id", event.ID, "type", event.Type, "sink", sink.Name(),
+			"attempts", attempts, "error", err)
+		nextAttemptAt := now.Add(RetryDelay(d.backoff, attempts))
+		if err = d.db.RetryOutboxDelivery(ctx, event.ID, sink.Name(), nextAttemptAt, err.Error()); err != nil {
+			return true, err
+		}
+		return true, nil
+	}
+	return len(deliveries) < dispatchBatchSize, nil
+}
+
+// RetryDelay doubles the base delay for every failed attempt after the first
+// one, up to one hour.
+func RetryDelay(base time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryBackoff)
+}
@@ -0,0 +1,228 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+	"zadanie-6105/config"
+	"zadanie-6105/database"
+	"zadanie-6105/model"
+)
+
+type deliveryKey struct {
+	eventID int64
+	sink    string
+}
+
+type deliveryState struct {
+	status        model.OutboxStatus
+	attempts      int
+	nextAttemptAt time.Time
+	lastError     string
+}
+
+// fakeOutboxDb keeps outbox_event and outbox_delivery in memory and mirrors
+// the queries of the postgres connector.
+type fakeOutboxDb struct {
+	database.DbConnector
+	events     []model.OutboxEvent
+	deliveries map[deliveryKey]*deliveryState
+}
+
+func newFakeOutboxDb(count int) *fakeOutboxDb {
+	db := &fakeOutboxDb{deliveries: make(map[deliveryKey]*deliveryState)}
+	created := time.Now().Add(-time.Minute)
+	for id := int64(1); id <= int64(count); id++ {
+		db.events = append(db.events, model.OutboxEvent{
+			ID: id, Type: model.EventTenderCreated, CreatedAt: created, Status: model.OutboxPending,
+		})
+	}
+	return db
+}
+
+func (db *fakeOutboxDb) RunExclusive(ctx context.Context, _ string, fn func(ctx context.Context) error) (bool, error) {
+	return true, fn(ctx)
+}
+
+func (db *fakeOutboxDb) GetPendingOutboxDeliveries(_ context.Context, sink string, limit int) ([]model.OutboxDelivery, error) {
+	var pending []model.OutboxDelivery
+	for _, event := range db.events {
+		if event.Status != model.OutboxPending || len(pending) == limit {
+			continue
+		}
+		delivery := model.OutboxDelivery{Event: event, Sink: sink, NextAttemptAt: event.CreatedAt}
+		if state, ok := db.deliveries[deliveryKey{event.ID, sink}]; ok {
+			if state.status != model.OutboxPending {
+				continue
+			}
+			delivery.Attempts = state.attempts
+			delivery.NextAttemptAt = state.nextAttemptAt
+		}
+		pending = append(pending, delivery)
+	}
+	return pending, nil
+}
+
+func (db *fakeOutboxDb) MarkOutboxDelivered(_ context.Context, eventID int64, sink string, sinks []string) error {
+	db.save(eventID, sink, model.OutboxDelivered, time.Now(), "")
+	db.complete(eventID, sinks)
+	return nil
+}
+
+func (db *fakeOutboxDb) RetryOutboxDelivery(
+	_ context.Context, eventID int64, sink string, nextAttemptAt time.Time, lastError string,
+) error {
+	db.save(eventID, sink, model.OutboxPending, nextAttemptAt, lastError)
+	return nil
+}
+
+func (db *fakeOutboxDb) DeadLetterOutboxDelivery(
+	_ context.Context, eventID int64, sink, lastError string, sinks []string,
+) error {
+	db.save(eventID, sink, model.OutboxDeadLetter, time.Now(), lastError)
+	db.complete(eventID, sinks)
+	return nil
+}
+
+func (db *fakeOutboxDb) save(eventID int64, sink string, status model.OutboxStatus, next time.Time, lastError string) {
+	key := deliveryKey{eventID, sink}
+	state, ok := db.deliveries[key]
+	if !ok {
+		state = &deliveryState{}
+		db.deliveries[key] = state
+	}
+	state.status = status
+	state.attempts++
+	state.nextAttemptAt = next
+	state.lastError = lastError
+}
+
+func (db *fakeOutboxDb) complete(eventID int64, sinks []string) {
+	status := model.OutboxDelivered
+	for _, sink := range sinks {
+		state, ok := db.deliveries[deliveryKey{eventID, sink}]
+		if !ok || state.status == model.OutboxPending {
+			return
+		}
+		if state.status == model.OutboxDeadLetter {
+			status = model.OutboxDeadLetter
+		}
+	}
+	db.events[eventID-1].Status = status
+}
+
+type recordingSink struct {
+	name      string
+	err       error
+	delivered []int64
+}
+
+func (s *recordingSink) Name() string {
+	return s.name
+}
+
+func (s *recordingSink) Deliver(_ context.Context, event *model.OutboxEvent) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.delivered = append(s.delivered, event.ID)
+	return nil
+}
+
+func newTestDispatcher(db database.DbConnector, maxAttempts int, sinks ...Sink) *Dispatcher {
+	cfg := &config.Config{
+		OutboxDispatchInterval: time.Second,
+		OutboxMaxAttempts:      maxAttempts,
+		OutboxRetryBackoff:     10 * time.Second,
+	}
+	return NewDispatcher(cfg, db, sinks...)
+}
+
+func TestDispatcherFailingSinkDoesNotBlockOthers(t *testing.T) {
+	db := newFakeOutboxDb(3)
+	webhooks := &recordingSink{name: "webhook"}
+	external := &recordingSink{name: "http", err: errors.New("connection refused")}
+	d := newTestDispatcher(db, 5, webhooks, external)
+
+	before := time.Now()
+	d.tick(context.Background())
+
+	if !slices.Equal(webhooks.delivered, []int64{1, 2, 3}) {
+		t.Errorf("webhook sink got %v, want [1 2 3]", webhooks.delivered)
+	}
+	state := db.deliveries[deliveryKey{1, "http"}]
+	if state == nil || state.status != model.OutboxPending || state.attempts != 1 {
+		t.Fatalf("http delivery of event 1 = %+v, want one pending attempt", state)
+	}
+	if delay := state.nextAttemptAt.Sub(before); delay < 10*time.Second || delay > 11*time.Second {
+		t.Errorf("next attempt in %v, want the base backoff of 10s", delay)
+	}
+	if _, ok := db.deliveries[deliveryKey{2, "http"}]; ok {
+		t.Error("http sink attempted event 2 before event 1 was delivered")
+	}
+	for _, event := range db.events {
+		if event.Status != model.OutboxPending {
+			t.Errorf("event %d is %s while the http sink still has it pending", event.ID, event.Status)
+		}
+	}
+
+	// Once the receiver recovers, only the pending sink gets the events.
+	external.err = nil
+	state.nextAttemptAt = time.Now()
+	d.tick(context.Background())
+
+	if !slices.Equal(external.delivered, []int64{1, 2, 3}) {
+		t.Errorf("http sink got %v, want [1 2 3]", external.delivered)
+	}
+	if !slices.Equal(webhooks.delivered, []int64{1, 2, 3}) {
+		t.Errorf("webhook sink got %v after the retry, want no redelivery", webhooks.delivered)
+	}
+	for _, event := range db.events {
+		if event.Status != model.OutboxDelivered {
+			t.Errorf("event %d is %s, want Delivered", event.ID, event.Status)
+		}
+	}
+}
+
+func TestDispatcherDeadLettersOnlyFailingSink(t *testing.T) {
+	db := newFakeOutboxDb(2)
+	webhooks := &recordingSink{name: "webhook"}
+	external := &recordingSink{name: "http", err: errors.New("status 500")}
+	d := newTestDispatcher(db, 1, webhooks, external)
+
+	d.tick(context.Background())
+
+	if !slices.Equal(webhooks.delivered, []int64{1, 2}) {
+		t.Errorf("webhook sink got %v, want [1 2]", webhooks.delivered)
+	}
+	for _, event := range db.events {
+		if event.Status != model.OutboxDeadLetter {
+			t.Errorf("event %d is %s, want DeadLetter", event.ID, event.Status)
+		}
+		if state := db.deliveries[deliveryKey{event.ID, "webhook"}]; state.status != model.OutboxDelivered {
+			t.Errorf("webhook delivery of event %d is %s, want Delivered", event.ID, state.status)
+		}
+		if state := db.deliveries[deliveryKey{event.ID, "http"}]; state.lastError != "status 500" {
+			t.Errorf("http delivery of event %d has last error %q", event.ID, state.lastError)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 10 * time.Second},
+		{attempts: 2, want: 20 * time.Second},
+		{attempts: 4, want: 80 * time.Second},
+		{attempts: 20, want: time.Hour},
+	}
+	for _, tt := range tests {
+		if got := RetryDelay(10*time.Second, tt.attempts); got != tt.want {
+			t.Errorf("RetryDelay(10s, %d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
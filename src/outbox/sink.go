@@ -0,0 +1,102 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"zadanie-6105/model"
+)
+
+// Sink delivers outbox events to a consumer. The dispatcher tracks delivery
+// for each sink by its Name, so names must be stable across restarts. Deliver
+// must be idempotent from the consumer's point of view: an event can be
+// delivered more than once when the process stops before the delivery is
+// recorded.
+type Sink interface {
+	Name() string
+	Deliver(ctx context.Context, event *model.OutboxEvent) error
+}
+
+// Envelope is the JSON document sent by the HTTP sink.
+type Envelope struct {
+	ID               int64           `json:"id"`
+	Type             model.EventType `json:"type"`
+	AggregateType    string          `json:"aggregateType"`
+	AggregateID      string          `json:"aggregateId"`
+	AggregateVersion int             `json:"aggregateVersion"`
+	OccurredAt       time.Time       `json:"occurredAt"`
+	Payload          json.RawMessage `json:"payload"`
+}
+
+func NewEnvelope(event *model.OutboxEvent) *Envelope {
+	return &Envelope{
+		ID:               event.ID,
+		Type:             event.Type,
+		AggregateType:    event.AggregateType,
+		AggregateID:      event.AggregateID,
+		AggregateVersion: event.AggregateVersion,
+		OccurredAt:       event.CreatedAt,
+		Payload:          event.Payload,
+	}
+}
+
+type logSink struct{}
+
+func NewLogSink() Sink {
+	return logSink{}
+}
+
+func (logSink) Name() string {
+	return "log"
+}
+
+func (logSink) Deliver(_ context.Context, event *model.OutboxEvent) error {
+	slog.Info("domain event", "id", event.ID, "type", event.Type, "aggregate_id", event.AggregateID,
+		"aggregate_version", event.AggregateVersion)
+	return nil
+}
+
+type httpSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPSink returns a sink that POSTs every event as an Envelope to url.
+// Any response other than 2xx is treated as a failed delivery.
+func NewHTTPSink(url string, timeout time.Duration) Sink {
+	return &httpSink{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (s *httpSink) Name() string {
+	return "http"
+}
+
+func (s *httpSink) Deliver(ctx context.Context, event *model.OutboxEvent) error {
+	body, err := json.Marshal(NewEnvelope(event))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", strconv.FormatInt(event.ID, 10))
+	req.Header.Set("X-Event-Type", string(event.Type))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+	"zadanie-6105/config"
+	"zadanie-6105/database"
+	"zadanie-6105/health"
+	"zadanie-6105/model"
+)
+
+const publishBatchSize = 100
+
+type Scheduler struct {
+	health.Heartbeat
+	db       database.DbConnector
+	interval time.Duration
+}
+
+func NewScheduler(cfg *config.Config, db database.DbConnector) *Scheduler {
+	return &Scheduler{
+		db:       db,
+		interval: cfg.SchedulerInterval,
+	}
+}
+
+func (s *Scheduler) Run(ctx context.Context) {
+	slog.Debug("start tender scheduler", "interval", s.interval)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.tick(ctx)
+		s.Beat()
+		select {
+		case <-ctx.Done():
+			slog.Debug("tender scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) tick(ctx context.Context) {
+	s.publishScheduledTenders(ctx)
+	s.closeExpiredTenders(ctx)
+}
+
+func (s *Scheduler) publishScheduledTenders(ctx context.Context) {
+	for {
+		var tenders []model.Tender
+		err := s.db.InTx(ctx, func(db database.DbConnector) error {
+			var err error
+			tenders, err = db.PublishScheduledTenders(ctx, time.Now(), publishBatchSize)
+			if err != nil {
+				return err
+			}
+			return audit(ctx, db, model.AuditTenderPublishScheduled, tenders)
+		})
+		if err != nil {
+			slog.Warn("error publishing scheduled tenders", "error", err)
+			return
+		}
+		for _, tender := range tenders {
+			slog.Info("scheduled tender published", "id", tender.ID, "version", tender.Version)
+		}
+		if len(tenders) < publishBatchSize {
+			return
+		}
+	}
+}
+
+func (s *Scheduler) closeExpiredTenders(ctx context.Context) {
+	var tenders []model.Tender
+	err := s.db.InTx(ctx, func(db database.DbConnector) error {
+		var err error
+		tenders, err = db.CloseExpiredTenders(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+		return audit(ctx, db, model.AuditTenderCloseExpired, tenders)
+	})
+	if err != nil {
+		slog.Warn("error closing expired tenders", "error", err)
+		return
+	}
+	for _, tender := range tenders {
+		slog.Info("tender closed by deadline", "id", tender.ID, "version", tender.Version)
+	}
+}
+
+// audit records changes made by the scheduler in the transaction that made
+// them. Such entries have no actor, request ID or client IP.
+func audit(ctx context.Context, db database.DbConnector, action string, tenders []model.Tender) error {
+	for _, tender := range tenders {
+		after, err := json.Marshal(tender)
+		if err != nil {
+			slog.Warn("error encoding audit snapshot", "error", err)
+		}
+		entry := &model.AuditEntry{
+			Action:         action,
+			EntityType:     model.EntityTender,
+			EntityID:       tender.ID,
+			EntityVersion:  &tender.Version,
+			OrganizationID: &tender.OrganizationID,
+			After:          after,
+		}
+		if _, err := db.SaveAuditEntry(ctx, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
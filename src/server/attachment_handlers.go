@@ -0,0 +1,511 @@
+package server
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/samber/lo"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"zadanie-6105/database"
+	"zadanie-6105/model"
+	"zadanie-6105/storage"
+)
+
+const (
+	attachmentFormField   = "file"
+	multipartOverhead     = 1 << 20
+	sniffLength           = 512
+	MaxAttachmentNameSize = 255
+)
+
+func (s *Server) uploadTenderAttachment(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	tenderId := mux.Vars(r)["tenderId"]
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUuid(tenderId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	tender, ok := s.getTender(w, r, tenderId)
+	if !ok {
+		return
+	}
+	isEmployeeInOrganization, err := s.db.IsEmployeeInOrganization(r.Context(), username, tender.OrganizationID)
+	if err != nil {
+		slog.Warn("error checking employee in organization", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error checking employee in organization"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if !isEmployeeInOrganization {
+		w.WriteHeader(http.StatusForbidden)
+		resp := ErrResponse{Reason: "user not in organization. Tender is not available"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	attachment, ok := s.receiveAttachment(w, r)
+	if !ok {
+		return
+	}
+	attachment.TenderID = tender.ID
+	attachment.TenderVersion = tender.Version
+	attachment.UploaderID = employee.ID
+	s.saveAttachment(w, r, username, tender.OrganizationID, attachment)
+}
+
+func (s *Server) uploadBidAttachment(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	bidId := mux.Vars(r)["bidId"]
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUuid(bidId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	bid, ok := s.getBid(w, r, bidId)
+	if !ok {
+		return
+	}
+	isAuthor, err := s.isBidAuthor(r, bid, employee)
+	if err != nil {
+		slog.Warn("error checking bid author", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error checking bid author"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if !isAuthor {
+		w.WriteHeader(http.StatusForbidden)
+		resp := ErrResponse{Reason: "only bid author can attach files"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	tender, ok := s.getTender(w, r, bid.TenderId)
+	if !ok {
+		return
+	}
+	attachment, ok := s.receiveAttachment(w, r)
+	if !ok {
+		return
+	}
+	attachment.TenderID = tender.ID
+	attachment.TenderVersion = tender.Version
+	attachment.BidID = &bid.ID
+	attachment.BidVersion = &bid.Version
+	attachment.UploaderID = employee.ID
+	s.saveAttachment(w, r, username, tender.OrganizationID, attachment)
+}
+
+func (s *Server) tenderAttachments(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	tenderId := mux.Vars(r)["tenderId"]
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUuid(tenderId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	tender, ok := s.getTender(w, r, tenderId)
+	if !ok {
+		return
+	}
+	if !s.checkTenderAvailable(w, r, tender, employee) {
+		return
+	}
+	version, ok := parseVersion(w, r, tender.Version)
+	if !ok {
+		return
+	}
+	attachments, err := s.db.GetTenderAttachments(r.Context(), tender.ID, version)
+	if err != nil {
+		slog.Warn("error getting tender attachments", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting attachments"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := attachmentsToResponse(attachments)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) bidAttachments(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	bidId := mux.Vars(r)["bidId"]
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUuid(bidId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	bid, ok := s.getBid(w, r, bidId)
+	if !ok {
+		return
+	}
+	if !s.checkBidAccess(w, r, bid, employee) {
+		return
+	}
+	version, ok := parseVersion(w, r, bid.Version)
+	if !ok {
+		return
+	}
+	attachments, err := s.db.GetBidAttachments(r.Context(), bid.ID, version)
+	if err != nil {
+		slog.Warn("error getting bid attachments", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting attachments"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := attachmentsToResponse(attachments)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) downloadAttachment(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	attachmentId := mux.Vars(r)["attachmentId"]
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUuid(attachmentId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	attachment, err := s.db.GetAttachmentByID(r.Context(), attachmentId)
+	if err != nil {
+		if errors.Is(err, database.ErrAttachmentNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			resp := ErrResponse{Reason: "attachment not found"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		slog.Warn("error getting attachment by id", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting attachment by id"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	tender, ok := s.getTender(w, r, attachment.TenderID)
+	if !ok {
+		return
+	}
+	if !s.checkTenderAvailable(w, r, tender, employee) {
+		return
+	}
+	if attachment.BidID != nil {
+		bid, ok := s.getBid(w, r, *attachment.BidID)
+		if !ok {
+			return
+		}
+		if !s.checkBidAccess(w, r, bid, employee) {
+			return
+		}
+	}
+	blob, err := s.blobs.Open(r.Context(), attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			resp := ErrResponse{Reason: "attachment content not found"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		slog.Warn("error opening attachment blob", "error", err, "key", attachment.StorageKey)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error reading attachment"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	defer func() { _ = blob.Close() }()
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("ETag", strconv.Quote(attachment.SHA256))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if _, err = io.Copy(w, blob); err != nil {
+		slog.Warn("error during write http response", "error", err)
+	}
+}
+
+func (s *Server) receiveAttachment(w http.ResponseWriter, r *http.Request) (*model.Attachment, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, s.attachments.MaxSize+multipartOverhead)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "request is not multipart/form-data"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return nil, false
+	}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			resp := ErrResponse{Reason: "file part is missing"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return nil, false
+		}
+		if part.FormName() != attachmentFormField {
+			continue
+		}
+		attachment, ok := s.storeAttachmentPart(w, r, part.FileName(), part)
+		_ = part.Close()
+		return attachment, ok
+	}
+}
+
+func (s *Server) storeAttachmentPart(
+	w http.ResponseWriter, r *http.Request, fileName string, part io.Reader,
+) (*model.Attachment, bool) {
+	fileName = filepath.Base(fileName)
+	if fileName == "" || fileName == "." || len(fileName) > MaxAttachmentNameSize {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "file name is not valid"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return nil, false
+	}
+	buffered := bufio.NewReaderSize(part, sniffLength)
+	head, err := buffered.Peek(sniffLength)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "error reading file"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return nil, false
+	}
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !slices.Contains(s.attachments.AllowedTypes, contentType) {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		resp := ErrResponse{Reason: "file type " + contentType + " is not allowed"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return nil, false
+	}
+	attachment := &model.Attachment{
+		ID:          uuid.NewString(),
+		FileName:    fileName,
+		ContentType: contentType,
+	}
+	attachment.StorageKey = attachment.ID
+	hasher := sha256.New()
+	limited := io.LimitReader(buffered, s.attachments.MaxSize+1)
+	attachment.Size, err = s.blobs.Put(r.Context(), attachment.StorageKey, io.TeeReader(limited, hasher))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			resp := ErrResponse{Reason: "file is too large. Max size is " + strconv.FormatInt(s.attachments.MaxSize, 10)}
+			_ = json.NewEncoder(w).Encode(resp)
+			return nil, false
+		}
+		slog.Warn("error storing attachment", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error storing attachment"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return nil, false
+	}
+	if attachment.Size > s.attachments.MaxSize {
+		_ = s.blobs.Delete(r.Context(), attachment.StorageKey)
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		resp := ErrResponse{Reason: "file is too large. Max size is " + strconv.FormatInt(s.attachments.MaxSize, 10)}
+		_ = json.NewEncoder(w).Encode(resp)
+		return nil, false
+	}
+	attachment.SHA256 = hex.EncodeToString(hasher.Sum(nil))
+	return attachment, true
+}
+
+func (s *Server) saveAttachment(
+	w http.ResponseWriter, r *http.Request, username, organizationId string, attachment *model.Attachment,
+) {
+	entry := &model.AuditEntry{
+		Action:         model.AuditAttachmentUpload,
+		EntityType:     model.EntityAttachment,
+		EntityID:       attachment.ID,
+		OrganizationID: &organizationId,
+	}
+	err := s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if _, err := db.SaveAttachment(r.Context(), attachment); err != nil {
+			return err
+		}
+		return s.audit(db, r, username, entry, nil, attachment)
+	})
+	if err != nil {
+		if errDelete := s.blobs.Delete(r.Context(), attachment.StorageKey); errDelete != nil {
+			slog.Warn("error deleting attachment blob", "error", errDelete, "key", attachment.StorageKey)
+		}
+		slog.Warn("error saving attachment", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error saving attachment"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := attachmentToResponse(attachment)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) checkBidAccess(w http.ResponseWriter, r *http.Request, bid *model.Bid, employee *model.Employee) bool {
+	isAuthor, err := s.isBidAuthor(r, bid, employee)
+	if err != nil {
+		slog.Warn("error checking bid author", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error checking bid author"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return false
+	}
+	if isAuthor {
+		return true
+	}
+	tender, ok := s.getTender(w, r, bid.TenderId)
+	if !ok {
+		return false
+	}
+	isResponsible, err := s.db.IsEmployeeInOrganization(r.Context(), employee.Username, tender.OrganizationID)
+	if err != nil {
+		slog.Warn("error checking employee in organization", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error checking employee in organization"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return false
+	}
+	if !isResponsible {
+		w.WriteHeader(http.StatusForbidden)
+		resp := ErrResponse{Reason: "bid is not available"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return false
+	}
+	return true
+}
+
+func (s *Server) isBidAuthor(r *http.Request, bid *model.Bid, employee *model.Employee) (bool, error) {
+	if bid.Author == model.AuthorUser {
+		return bid.AuthorId == employee.ID, nil
+	}
+	return s.db.IsEmployeeInOrganization(r.Context(), employee.Username, bid.AuthorId)
+}
+
+func (s *Server) getEmployee(w http.ResponseWriter, r *http.Request, username string) (*model.Employee, bool) {
+	employee, err := s.db.GetEmployeeByUsername(r.Context(), username)
+	if err != nil {
+		if errors.Is(err, database.ErrEmployeeNotFound) {
+			w.WriteHeader(http.StatusUnauthorized)
+			resp := ErrResponse{Reason: "employee does not exist"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return nil, false
+		}
+		slog.Warn("error getting employee by username", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting employee by username"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return nil, false
+	}
+	return employee, true
+}
+
+func (s *Server) getTender(w http.ResponseWriter, r *http.Request, tenderId string) (*model.Tender, bool) {
+	tender, err := s.db.GetTenderByID(r.Context(), tenderId)
+	if err != nil {
+		if errors.Is(err, database.ErrTenderNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			resp := ErrResponse{Reason: "tender not found"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return nil, false
+		}
+		slog.Warn("error getting tender by id", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting tender by id"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return nil, false
+	}
+	return tender, true
+}
+
+func (s *Server) getBid(w http.ResponseWriter, r *http.Request, bidId string) (*model.Bid, bool) {
+	bid, err := s.db.GetBidByID(r.Context(), bidId)
+	if err != nil {
+		if errors.Is(err, database.ErrBidNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			resp := ErrResponse{Reason: "bid not found"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return nil, false
+		}
+		slog.Warn("error getting bid by id", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting bid by id"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return nil, false
+	}
+	return bid, true
+}
+
+func parseVersion(w http.ResponseWriter, r *http.Request, latest int) (int, bool) {
+	versionStr := r.URL.Query().Get("version")
+	if versionStr == "" {
+		return latest, true
+	}
+	version, err := strconv.Atoi(versionStr)
+	if err != nil || version < 1 || version > latest {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "version is not valid"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return 0, false
+	}
+	return version, true
+}
+
+func attachmentsToResponse(attachments []model.Attachment) []*AttachmentResponse {
+	return lo.Map(attachments, func(attachment model.Attachment, _ int) *AttachmentResponse {
+		return attachmentToResponse(&attachment)
+	})
+}
+
+func attachmentToResponse(a *model.Attachment) *AttachmentResponse {
+	return &AttachmentResponse{
+		ID:            a.ID,
+		TenderID:      a.TenderID,
+		TenderVersion: a.TenderVersion,
+		BidID:         a.BidID,
+		BidVersion:    a.BidVersion,
+		FileName:      a.FileName,
+		ContentType:   a.ContentType,
+		Size:          a.Size,
+		SHA256:        a.SHA256,
+		DownloadURL:   "/api/attachments/" + a.ID,
+		CreatedAt:     JSONTime(a.CreatedAt),
+	}
+}
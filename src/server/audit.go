@@ -0,0 +1,175 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/samber/lo"
+	"log/slog"
+	"net"
+	"net/http"
+	"zadanie-6105/chain"
+	"zadanie-6105/database"
+	"zadanie-6105/model"
+)
+
+const (
+	RequestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 100
+)
+
+type requestIDKey struct{}
+
+// requestIDMiddleware keeps the caller's X-Request-ID or generates a new one,
+// echoes it in the response and makes it available to handlers.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// audit appends an entry for a mutation. before and after are snapshots of
+// the target entity and may be nil. db is the transaction of the mutation
+// (see DbConnector.InTx), so the entry is committed together with the change
+// and a failure to write it rolls the change back.
+func (s *Server) audit(db database.DbConnector, r *http.Request, username string, entry *model.AuditEntry, before, after any) error {
+	entry.ActorUsername = &username
+	entry.RequestID = lo.ToPtr(requestID(r))
+	entry.ClientIP = lo.ToPtr(clientIP(r))
+	entry.Before = auditSnapshot(before)
+	entry.After = auditSnapshot(after)
+	_, err := db.SaveAuditEntry(r.Context(), entry)
+	return err
+}
+
+func auditSnapshot(v any) []byte {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		slog.Warn("error encoding audit snapshot", "error", err)
+		return nil
+	}
+	return data
+}
+
+func tenderAuditEntry(action string, tender *model.Tender) *model.AuditEntry {
+	return &model.AuditEntry{
+		Action:         action,
+		EntityType:     model.EntityTender,
+		EntityID:       tender.ID,
+		EntityVersion:  lo.ToPtr(tender.Version),
+		OrganizationID: lo.ToPtr(tender.OrganizationID),
+	}
+}
+
+func (s *Server) auditEntries(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	organizationId := mux.Vars(r)["organizationId"]
+	username := r.URL.Query().Get("username")
+	ok, limit, offset := validator.ValidatePagination(r.URL.Query().Get("limit"), r.URL.Query().Get("offset"))
+	if !ok {
+		return
+	}
+	if !validator.ValidateUuid(organizationId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	filter := &database.AuditFilter{
+		Limit:          limit,
+		Offset:         offset,
+		OrganizationID: organizationId,
+		Action:         r.URL.Query().Get("action"),
+		EntityType:     r.URL.Query().Get("entity_type"),
+		EntityID:       r.URL.Query().Get("entity_id"),
+		ActorUsername:  r.URL.Query().Get("actor_username"),
+	}
+	if !validator.parseTime(r.URL.Query().Get("from"), "from", &filter.From) {
+		return
+	}
+	if !validator.parseTime(r.URL.Query().Get("to"), "to", &filter.To) {
+		return
+	}
+	if !s.checkOrganizationResponsible(w, r, username, organizationId) {
+		return
+	}
+	entries, err := s.db.GetAuditEntries(r.Context(), filter)
+	if err != nil {
+		slog.Warn("error getting audit entries", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting audit entries"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := lo.Map(entries, func(entry model.AuditEntry, _ int) *AuditEntryResponse {
+		return auditEntryToResponse(&entry)
+	})
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+// verifyChains recomputes the tender, bid and audit hash chains and reports the
+// first broken link of each.
+func (s *Server) verifyChains(w http.ResponseWriter, r *http.Request) {
+	username := r.URL.Query().Get("username")
+	if !s.validateAdmin(w, r, username) {
+		return
+	}
+	reports, err := chain.VerifyAll(r.Context(), s.db)
+	if err != nil {
+		slog.Warn("error verifying hash chains", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error verifying hash chains"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := lo.Map(reports, func(report *chain.Report, _ int) *ChainReportResponse {
+		resp := &ChainReportResponse{Chain: report.Chain, Checked: report.Checked, Valid: report.Valid()}
+		if report.Broken != nil {
+			resp.Broken = &BrokenLinkResponse{ID: report.Broken.ID, Seq: report.Broken.Seq, Reason: report.Broken.Reason}
+		}
+		return resp
+	})
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func auditEntryToResponse(entry *model.AuditEntry) *AuditEntryResponse {
+	return &AuditEntryResponse{
+		ID:             entry.ID,
+		OccurredAt:     JSONTime(entry.OccurredAt),
+		ActorID:        entry.ActorID,
+		ActorUsername:  entry.ActorUsername,
+		Action:         entry.Action,
+		EntityType:     entry.EntityType,
+		EntityID:       entry.EntityID,
+		EntityVersion:  entry.EntityVersion,
+		OrganizationID: entry.OrganizationID,
+		RequestID:      entry.RequestID,
+		ClientIP:       entry.ClientIP,
+		Before:         entry.Before,
+		After:          entry.After,
+	}
+}
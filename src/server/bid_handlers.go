@@ -0,0 +1,597 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/samber/lo"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+	"zadanie-6105/database"
+	"zadanie-6105/model"
+)
+
+const (
+	compareByPrice        = "price"
+	compareByDeliveryTime = "deliveryTime"
+)
+
+func (s *Server) compareBids(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	tenderId := mux.Vars(r)["tenderId"]
+	username := r.URL.Query().Get("username")
+	sortBy := r.URL.Query().Get("sort_by")
+	lotId := r.URL.Query().Get("lot_id")
+	if !validator.ValidateUuid(tenderId) {
+		return
+	}
+	if lotId != "" && !validator.ValidateUuid(lotId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	if sortBy == "" {
+		sortBy = compareByPrice
+	}
+	if sortBy != compareByPrice && sortBy != compareByDeliveryTime {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "sort_by is not valid"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	tender, err := s.db.GetTenderByID(r.Context(), tenderId)
+	if err != nil {
+		if errors.Is(err, database.ErrTenderNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			resp := ErrResponse{Reason: "tender not found"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		slog.Warn("error getting tender by id", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting tender by id"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	isEmployeeInOrganization, err := s.db.IsEmployeeInOrganization(r.Context(), username, tender.OrganizationID)
+	if err != nil {
+		slog.Warn("error checking employee in organization", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error checking employee in organization"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if !isEmployeeInOrganization {
+		w.WriteHeader(http.StatusForbidden)
+		resp := ErrResponse{Reason: "user not in organization. Bids are not available"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	bids, err := s.db.GetPublishedBidsByTenderID(r.Context(), tenderId)
+	if err != nil {
+		slog.Warn("error getting bids by tender id", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting bids"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if lotId != "" {
+		bids = lo.Filter(bids, func(bid model.Bid, _ int) bool {
+			return bid.LotID != nil && *bid.LotID == lotId
+		})
+	}
+	resp := rankBids(bids, sortBy)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+type rankedBid struct {
+	bid   *model.Bid
+	price *big.Rat
+}
+
+func rankBids(bids []model.Bid, sortBy string) []*BidComparisonResponse {
+	ranked := make([]rankedBid, 0, len(bids))
+	for i := range bids {
+		rb := rankedBid{bid: &bids[i]}
+		if bids[i].Price != nil {
+			rb.price, _ = new(big.Rat).SetString(bids[i].Price.Amount)
+		}
+		ranked = append(ranked, rb)
+	}
+	priceRanks := competitionRanks(ranked, func(a, b rankedBid) int {
+		return a.price.Cmp(b.price)
+	}, func(rb rankedBid) (string, bool) {
+		if rb.price == nil {
+			return "", false
+		}
+		return rb.bid.Price.Currency, true
+	})
+	deliveryRanks := competitionRanks(ranked, func(a, b rankedBid) int {
+		return *a.bid.DeliveryDays - *b.bid.DeliveryDays
+	}, func(rb rankedBid) (string, bool) {
+		return "", rb.bid.DeliveryDays != nil
+	})
+
+	order := make([]int, len(ranked))
+	for i := range order {
+		order[i] = i
+	}
+	byPrice := func(a, b int) int {
+		return compareOptionalRanks(priceRanks[a], priceRanks[b])
+	}
+	byDelivery := func(a, b int) int {
+		return compareOptionalRanks(deliveryRanks[a], deliveryRanks[b])
+	}
+	primary, secondary := byPrice, byDelivery
+	if sortBy == compareByDeliveryTime {
+		primary, secondary = byDelivery, byPrice
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if c := primary(a, b); c != 0 {
+			return c < 0
+		}
+		if c := secondary(a, b); c != 0 {
+			return c < 0
+		}
+		return ranked[a].bid.Name < ranked[b].bid.Name
+	})
+
+	resp := make([]*BidComparisonResponse, 0, len(order))
+	for position, i := range order {
+		bid := ranked[i].bid
+		resp = append(resp, &BidComparisonResponse{
+			Rank:         position + 1,
+			PriceRank:    priceRanks[i],
+			DeliveryRank: deliveryRanks[i],
+			ID:           bid.ID,
+			Name:         bid.Name,
+			Description:  bid.Description,
+			AuthorType:   string(bid.Author),
+			AuthorID:     bid.AuthorId,
+			Version:      bid.Version,
+			CreatedAt:    JSONTime(bid.CratedAt),
+			Price:        moneyToResponse(bid.Price),
+			DeliveryDays: bid.DeliveryDays,
+			Terms:        bid.Terms,
+			LotID:        bid.LotID,
+		})
+	}
+	return resp
+}
+
+// competitionRanks ranks bids as 1, 2, 2, 4, ... within their group. Prices in different currencies are not comparable,
+// so they are ranked separately. Bids without a group get no rank.
+func competitionRanks(
+	bids []rankedBid, cmp func(a, b rankedBid) int, group func(rb rankedBid) (string, bool),
+) []*int {
+	ranks := make([]*int, len(bids))
+	for i := range bids {
+		groupI, ok := group(bids[i])
+		if !ok {
+			continue
+		}
+		rank := 1
+		for j := range bids {
+			if groupJ, ok := group(bids[j]); ok && groupJ == groupI && cmp(bids[j], bids[i]) < 0 {
+				rank++
+			}
+		}
+		ranks[i] = &rank
+	}
+	return ranks
+}
+
+func compareOptionalRanks(a, b *int) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	default:
+		return *a - *b
+	}
+}
+
+// newBid creates a bid in the Created status. The author is a user or an
+// organization; responsibles of the tender organization see the bid once it is
+// published.
+func (s *Server) newBid(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	var req BidRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Warn("error decoding body", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "error decoding body"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if !validateBidRequest(w, &req) {
+		return
+	}
+	if !validator.ValidateUuid(req.TenderId) || !validator.ValidateUuid(req.AuthorId) {
+		return
+	}
+	actor, ok := s.getBidAuthor(w, r, model.AuthorType(req.AuthorType), req.AuthorId)
+	if !ok {
+		return
+	}
+	tender, ok := s.getTender(w, r, req.TenderId)
+	if !ok {
+		return
+	}
+	if !s.checkTenderAcceptingBids(w, r, tender, model.AuthorType(req.AuthorType), req.AuthorId) {
+		return
+	}
+	if req.LotId != nil && !s.checkBidLot(w, r, *req.LotId, tender) {
+		return
+	}
+	bid := &model.Bid{
+		Name:         req.Name,
+		Description:  req.Description,
+		Status:       model.BidCreated,
+		Author:       model.AuthorType(req.AuthorType),
+		AuthorId:     req.AuthorId,
+		TenderId:     tender.ID,
+		Price:        requestToMoney(req.Price),
+		DeliveryDays: req.DeliveryDays,
+		Terms:        req.Terms,
+		LotID:        req.LotId,
+	}
+	err := s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if _, err := db.SaveBid(r.Context(), bid); err != nil {
+			return err
+		}
+		return s.audit(db, r, actor, bidAuditEntry(model.AuditBidCreate, bid, tender), nil, bid)
+	})
+	if err != nil {
+		s.writeBidError(w, err, "error saving bid")
+		return
+	}
+	resp := bidToResponse(bid)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+// updateBidStatus lets the author publish or cancel a bid. Publishing submits
+// the bid to the tender organization, so it is allowed only while the tender
+// accepts bids.
+func (s *Server) updateBidStatus(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	bidId := mux.Vars(r)["bidId"]
+	username := r.URL.Query().Get("username")
+	status := r.URL.Query().Get("status")
+	if !validator.ValidateUuid(bidId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	if !IsValidBidStatus(status) {
+		validator.writeBadRequest("status is not valid")
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	bid, ok := s.getBid(w, r, bidId)
+	if !ok {
+		return
+	}
+	isAuthor, err := s.isBidAuthor(r, bid, employee)
+	if err != nil {
+		slog.Warn("error checking bid author", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error checking bid author"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if !isAuthor {
+		w.WriteHeader(http.StatusForbidden)
+		resp := ErrResponse{Reason: "bid is not available"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	tender, ok := s.getTender(w, r, bid.TenderId)
+	if !ok {
+		return
+	}
+	var events []model.EventType
+	if model.BidStatus(status) == model.BidPublished && bid.Status != model.BidPublished {
+		if !s.checkTenderAcceptingBids(w, r, tender, bid.Author, bid.AuthorId) {
+			return
+		}
+		events = append(events, model.EventBidSubmitted)
+	}
+	before := *bid
+	bid.Status = model.BidStatus(status)
+	err = s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if _, err := db.UpdateBid(r.Context(), bid, events...); err != nil {
+			return err
+		}
+		return s.audit(db, r, username, bidAuditEntry(model.AuditBidStatus, bid, tender), &before, bid)
+	})
+	if err != nil {
+		s.writeBidError(w, err, "error updating bid")
+		return
+	}
+	resp := bidToResponse(bid)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+// submitBidDecision records a decision of a tender responsible. A rejection
+// rejects the bid at once; an approval takes effect when the quorum of
+// min(3, number of responsibles) is reached, and closes the tender.
+func (s *Server) submitBidDecision(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	bidId := mux.Vars(r)["bidId"]
+	username := r.URL.Query().Get("username")
+	decision := r.URL.Query().Get("decision")
+	if !validator.ValidateUuid(bidId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	if !IsValidBidDecision(decision) {
+		validator.writeBadRequest("decision is not valid")
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	bid, ok := s.getBid(w, r, bidId)
+	if !ok {
+		return
+	}
+	tender, ok := s.getTender(w, r, bid.TenderId)
+	if !ok {
+		return
+	}
+	if !s.checkTenderResponsible(w, r, username, tender) {
+		return
+	}
+	err := s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		outcome, err := db.SaveBidDecision(r.Context(), bid, employee.ID, model.BidDecision(decision))
+		if err != nil {
+			return err
+		}
+		err = s.audit(db, r, username, bidAuditEntry(model.AuditBidDecision, bid, tender), nil, bidDecisionSnapshot{
+			Decision: model.BidDecision(decision),
+			Outcome:  outcome,
+		})
+		if err != nil || outcome != model.BidApproved {
+			return err
+		}
+		return s.awardBid(db, r, username, bid, tender)
+	})
+	if err != nil {
+		s.writeBidError(w, err, "error saving bid decision")
+		return
+	}
+	resp := bidToResponse(bid)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+type bidDecisionSnapshot struct {
+	Decision model.BidDecision `json:"decision"`
+	Outcome  model.BidDecision `json:"outcome,omitempty"`
+}
+
+// awardBid awards the lot of an approved bid through CloseLot, which closes
+// the tender after its last open lot. A bid without a lot closes the tender
+// unless some lot of it is still open.
+func (s *Server) awardBid(
+	db database.DbConnector, r *http.Request, username string, bid *model.Bid, tender *model.Tender,
+) error {
+	var closed *model.Tender
+	action := model.AuditTenderCloseAward
+	if bid.LotID != nil {
+		before, err := db.GetLotByID(r.Context(), *bid.LotID)
+		if err != nil {
+			return err
+		}
+		lot, closedTender, err := db.CloseLot(r.Context(), before.ID, model.LotAwarded, &bid.ID)
+		if err != nil {
+			return err
+		}
+		if err = s.audit(db, r, username, lotAuditEntry(model.AuditLotStatus, lot, tender), before, lot); err != nil {
+			return err
+		}
+		closed, action = closedTender, model.AuditTenderCloseLots
+	} else {
+		var err error
+		if closed, err = db.CloseTenderIfLotsClosed(r.Context(), tender.ID); err != nil {
+			return err
+		}
+	}
+	if closed == nil {
+		return nil
+	}
+	return s.audit(db, r, username, tenderAuditEntry(action, closed), tender, closed)
+}
+
+// checkBidLot writes an error unless the lot belongs to the tender and is
+// still open.
+func (s *Server) checkBidLot(w http.ResponseWriter, r *http.Request, lotId string, tender *model.Tender) bool {
+	validator := NewValidator(w, r, s.db)
+	if !validator.ValidateUuid(lotId) {
+		return false
+	}
+	lot, ok := s.getLot(w, r, lotId)
+	if !ok {
+		return false
+	}
+	if lot.TenderID != tender.ID {
+		validator.writeBadRequest("lot does not belong to the tender")
+		return false
+	}
+	if lot.Status != model.LotOpen {
+		s.writeLotError(w, database.ErrLotClosed, "")
+		return false
+	}
+	return true
+}
+
+// getBidAuthor checks that the author of a new bid exists and returns the
+// username to record in the audit log, which is empty for organizations.
+func (s *Server) getBidAuthor(
+	w http.ResponseWriter, r *http.Request, authorType model.AuthorType, authorId string,
+) (string, bool) {
+	if authorType == model.AuthorUser {
+		employee, err := s.db.GetEmployeeByID(r.Context(), authorId)
+		if err == nil {
+			return employee.Username, true
+		}
+		if errors.Is(err, database.ErrEmployeeNotFound) {
+			w.WriteHeader(http.StatusUnauthorized)
+			resp := ErrResponse{Reason: "employee does not exist"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return "", false
+		}
+		slog.Warn("error getting employee by id", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting employee by id"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return "", false
+	}
+	if _, err := s.db.GetOrganizationById(r.Context(), authorId); err != nil {
+		if errors.Is(err, database.ErrOrganizationNotFound) {
+			w.WriteHeader(http.StatusUnauthorized)
+			resp := ErrResponse{Reason: "organization does not exist"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return "", false
+		}
+		slog.Warn("error getting organization by id", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting organization by id"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return "", false
+	}
+	return "", true
+}
+
+// checkTenderAcceptingBids writes 403 unless the tender accepts bids from the
+// author. Invite-only tenders accept bids from the tender organization, the
+// invited organizations and their responsibles.
+func (s *Server) checkTenderAcceptingBids(
+	w http.ResponseWriter, r *http.Request, tender *model.Tender, authorType model.AuthorType, authorId string,
+) bool {
+	invited := false
+	if tender.Visibility == model.TenderInviteOnly {
+		var err error
+		if authorType == model.AuthorUser {
+			invited, err = s.db.IsTenderInvitee(r.Context(), tender.ID, tender.OrganizationID, authorId)
+		} else {
+			var invitations []model.TenderInvitation
+			invitations, err = s.db.GetTenderInvitations(r.Context(), tender.ID)
+			invited = authorId == tender.OrganizationID || lo.ContainsBy(invitations, func(inv model.TenderInvitation) bool {
+				return inv.OrganizationID == authorId
+			})
+		}
+		if err != nil {
+			slog.Warn("error checking tender invitation", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			resp := ErrResponse{Reason: "error checking tender invitation"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return false
+		}
+	}
+	if !IsTenderAcceptingBids(tender, invited, time.Now()) {
+		w.WriteHeader(http.StatusForbidden)
+		resp := ErrResponse{Reason: "tender is not accepting bids"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return false
+	}
+	return true
+}
+
+func (s *Server) writeBidError(w http.ResponseWriter, err error, reason string) {
+	switch {
+	case errors.Is(err, database.ErrBidNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		resp := ErrResponse{Reason: "bid not found"}
+		_ = json.NewEncoder(w).Encode(resp)
+	case errors.Is(err, database.ErrTenderNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		resp := ErrResponse{Reason: "tender not found"}
+		_ = json.NewEncoder(w).Encode(resp)
+	case errors.Is(err, database.ErrLotNotFound), errors.Is(err, database.ErrLotClosed):
+		s.writeLotError(w, err, reason)
+	case errors.Is(err, database.ErrBidNotPublished), errors.Is(err, database.ErrBidDecided),
+		errors.Is(err, database.ErrBidDecisionExists), errors.Is(err, database.ErrTenderNotPublished):
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: err.Error()}
+		_ = json.NewEncoder(w).Encode(resp)
+	default:
+		slog.Warn(reason, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: reason}
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func validateBidRequest(w http.ResponseWriter, req *BidRequest) bool {
+	reason := ""
+	switch {
+	case req.Name == "" || len(req.Name) > MaxBidNameLength:
+		reason = "name is empty or too long. Max length is " + strconv.Itoa(MaxBidNameLength)
+	case req.Description == "" || len(req.Description) > MaxBidDescLength:
+		reason = "description is empty or too long. Max length is " + strconv.Itoa(MaxBidDescLength)
+	case !IsValidAuthorType(req.AuthorType):
+		reason = "author type is not valid"
+	case !IsValidMoney(req.Price):
+		reason = "price is not valid"
+	case req.DeliveryDays != nil && *req.DeliveryDays <= 0:
+		reason = "delivery days must be positive"
+	case len(req.Terms) > MaxBidTermsLength:
+		reason = "terms are too long. Max length is " + strconv.Itoa(MaxBidTermsLength)
+	}
+	if reason != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: reason}
+		_ = json.NewEncoder(w).Encode(resp)
+		return false
+	}
+	return true
+}
+
+func bidAuditEntry(action string, bid *model.Bid, tender *model.Tender) *model.AuditEntry {
+	return &model.AuditEntry{
+		Action:         action,
+		EntityType:     model.EntityBid,
+		EntityID:       bid.ID,
+		EntityVersion:  lo.ToPtr(bid.Version),
+		OrganizationID: lo.ToPtr(tender.OrganizationID),
+	}
+}
+
+func bidToResponse(bid *model.Bid) *BidResponse {
+	return &BidResponse{
+		ID:           bid.ID,
+		Name:         bid.Name,
+		Description:  bid.Description,
+		Status:       string(bid.Status),
+		TenderID:     bid.TenderId,
+		AuthorType:   string(bid.Author),
+		AuthorID:     bid.AuthorId,
+		Version:      bid.Version,
+		CreatedAt:    JSONTime(bid.CratedAt),
+		Price:        moneyToResponse(bid.Price),
+		DeliveryDays: bid.DeliveryDays,
+		Terms:        bid.Terms,
+		LotID:        bid.LotID,
+	}
+}
@@ -0,0 +1,375 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+	"zadanie-6105/config"
+	"zadanie-6105/database"
+	"zadanie-6105/metrics"
+	"zadanie-6105/model"
+)
+
+const (
+	testOrganizationID = "00000000-0000-0000-0000-0000000000a1"
+	testTenderID       = "00000000-0000-0000-0000-0000000000b1"
+)
+
+var (
+	testAuthor      = model.Employee{ID: "00000000-0000-0000-0000-0000000000c1", Username: "author"}
+	testResponsible = model.Employee{ID: "00000000-0000-0000-0000-0000000000c2", Username: "responsible"}
+)
+
+// fakeBidDb keeps one tender with its lots and bids in memory and mirrors the
+// queries of the postgres connector. Every transaction runs on the same fake.
+// testResponsible is the only responsible, so one approval reaches the quorum.
+type fakeBidDb struct {
+	database.DbConnector
+	tender    *model.Tender
+	lots      []*model.Lot
+	bids      []model.Bid
+	decisions map[string]model.BidDecision
+	audit     []model.AuditEntry
+}
+
+func newFakeBidDb() *fakeBidDb {
+	return &fakeBidDb{tender: &model.Tender{
+		ID:             testTenderID,
+		Name:           "Roadworks",
+		Status:         model.TenderPublished,
+		OrganizationID: testOrganizationID,
+		CreatorID:      testResponsible.ID,
+		Version:        1,
+		Visibility:     model.TenderPublic,
+	}, decisions: make(map[string]model.BidDecision)}
+}
+
+func (db *fakeBidDb) InTx(_ context.Context, fn func(db database.DbConnector) error) error {
+	return fn(db)
+}
+
+func (db *fakeBidDb) employee(match func(e model.Employee) bool) (*model.Employee, error) {
+	for _, e := range []model.Employee{testAuthor, testResponsible} {
+		if match(e) {
+			return &e, nil
+		}
+	}
+	return nil, database.ErrEmployeeNotFound
+}
+
+func (db *fakeBidDb) GetEmployeeByID(_ context.Context, id string) (*model.Employee, error) {
+	return db.employee(func(e model.Employee) bool { return e.ID == id })
+}
+
+func (db *fakeBidDb) GetEmployeeByUsername(_ context.Context, username string) (*model.Employee, error) {
+	return db.employee(func(e model.Employee) bool { return e.Username == username })
+}
+
+func (db *fakeBidDb) IsEmployeeExists(_ context.Context, username string) (bool, error) {
+	_, err := db.GetEmployeeByUsername(context.Background(), username)
+	return err == nil, nil
+}
+
+func (db *fakeBidDb) IsEmployeeInOrganization(_ context.Context, username, organizationID string) (bool, error) {
+	return username == testResponsible.Username && organizationID == testOrganizationID, nil
+}
+
+func (db *fakeBidDb) GetTenderByID(_ context.Context, id string) (*model.Tender, error) {
+	if id != db.tender.ID {
+		return nil, database.ErrTenderNotFound
+	}
+	tender := *db.tender
+	return &tender, nil
+}
+
+func (db *fakeBidDb) SaveBid(_ context.Context, bid *model.Bid) (*model.Bid, error) {
+	bid.ID = uuid.NewString()
+	bid.Version = 1
+	bid.CratedAt = time.Now()
+	db.bids = append(db.bids, *bid)
+	return bid, nil
+}
+
+func (db *fakeBidDb) UpdateBid(_ context.Context, bid *model.Bid, _ ...model.EventType) (*model.Bid, error) {
+	bid.Version++
+	db.bids = append(db.bids, *bid)
+	return bid, nil
+}
+
+func (db *fakeBidDb) GetBidByID(_ context.Context, id string) (*model.Bid, error) {
+	for i := len(db.bids) - 1; i >= 0; i-- {
+		if db.bids[i].ID == id {
+			bid := db.bids[i]
+			return &bid, nil
+		}
+	}
+	return nil, database.ErrBidNotFound
+}
+
+func (db *fakeBidDb) GetPublishedBidsByTenderID(_ context.Context, tenderID string) ([]model.Bid, error) {
+	var bids []model.Bid
+	for _, bid := range db.bids {
+		current, _ := db.GetBidByID(context.Background(), bid.ID)
+		if bid.TenderId == tenderID && bid.Version == current.Version && bid.Status == model.BidPublished {
+			bids = append(bids, bid)
+		}
+	}
+	return bids, nil
+}
+
+func (db *fakeBidDb) SaveBidDecision(
+	_ context.Context, bid *model.Bid, _ string, decision model.BidDecision,
+) (model.BidDecision, error) {
+	if bid.Status != model.BidPublished {
+		return "", database.ErrBidNotPublished
+	}
+	if db.tender.Status != model.TenderPublished {
+		return "", database.ErrTenderNotPublished
+	}
+	if _, ok := db.decisions[bid.ID]; ok {
+		return "", database.ErrBidDecided
+	}
+	db.decisions[bid.ID] = decision
+	return decision, nil
+}
+
+func (db *fakeBidDb) GetLotByID(_ context.Context, id string) (*model.Lot, error) {
+	for _, lot := range db.lots {
+		if lot.ID == id {
+			found := *lot
+			return &found, nil
+		}
+	}
+	return nil, database.ErrLotNotFound
+}
+
+func (db *fakeBidDb) CloseLot(
+	ctx context.Context, id string, status model.LotStatus, awardedBidID *string,
+) (*model.Lot, *model.Tender, error) {
+	for _, lot := range db.lots {
+		if lot.ID != id {
+			continue
+		}
+		if lot.Status != model.LotOpen {
+			return nil, nil, database.ErrLotClosed
+		}
+		lot.Status, lot.AwardedBidID = status, awardedBidID
+		closed, err := db.CloseTenderIfLotsClosed(ctx, lot.TenderID)
+		found := *lot
+		return &found, closed, err
+	}
+	return nil, nil, database.ErrLotNotFound
+}
+
+func (db *fakeBidDb) CloseTenderIfLotsClosed(_ context.Context, _ string) (*model.Tender, error) {
+	if db.tender.Status == model.TenderClosed {
+		return nil, nil
+	}
+	for _, lot := range db.lots {
+		if lot.Status == model.LotOpen {
+			return nil, nil
+		}
+	}
+	db.tender.Status = model.TenderClosed
+	db.tender.Version++
+	closed := *db.tender
+	return &closed, nil
+}
+
+func (db *fakeBidDb) SaveAuditEntry(_ context.Context, e *model.AuditEntry) (*model.AuditEntry, error) {
+	db.audit = append(db.audit, *e)
+	return e, nil
+}
+
+// bidJSON decodes the fields of BidResponse and BidComparisonResponse the
+// tests check; JSONTime can only be encoded.
+type bidJSON struct {
+	ID           string         `json:"id"`
+	Name         string         `json:"name"`
+	Rank         int            `json:"rank"`
+	PriceRank    *int           `json:"priceRank"`
+	DeliveryRank *int           `json:"deliveryRank"`
+	Price        *MoneyResponse `json:"price"`
+	DeliveryDays *int           `json:"deliveryDays"`
+	Terms        string         `json:"terms"`
+}
+
+func newTestServer(db database.DbConnector) *Server {
+	return NewServer(&config.Config{}, db, nil, nil, nil, metrics.NewMetrics(db))
+}
+
+func serve(t *testing.T, s *Server, method, target string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rec := httptest.NewRecorder()
+	s.Router().ServeHTTP(rec, httptest.NewRequest(method, target, &buf))
+	return rec
+}
+
+// submitBid creates a bid of testAuthor and publishes it.
+func submitBid(t *testing.T, s *Server, req BidRequest) string {
+	t.Helper()
+	req.TenderId, req.AuthorType, req.AuthorId = testTenderID, string(model.AuthorUser), testAuthor.ID
+	rec := serve(t, s, http.MethodPost, "/api/bids/new", req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /api/bids/new = %d %s", rec.Code, rec.Body)
+	}
+	var created bidJSON
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	rec = serve(t, s, http.MethodPut, "/api/bids/"+created.ID+"/status?status=Published&username=author", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("publishing bid = %d %s", rec.Code, rec.Body)
+	}
+	return created.ID
+}
+
+func TestNewBidReturnsPriceAndTerms(t *testing.T) {
+	s := newTestServer(newFakeBidDb())
+	rec := serve(t, s, http.MethodPost, "/api/bids/new", BidRequest{
+		Name:         "Asphalt",
+		Description:  "Two layers",
+		TenderId:     testTenderID,
+		AuthorType:   string(model.AuthorUser),
+		AuthorId:     testAuthor.ID,
+		Price:        &MoneyRequest{Amount: "1500.50", Currency: "RUB"},
+		DeliveryDays: new(int),
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("zero delivery days: status = %d, want 400", rec.Code)
+	}
+
+	days := 14
+	rec = serve(t, s, http.MethodPost, "/api/bids/new", BidRequest{
+		Name:         "Asphalt",
+		Description:  "Two layers",
+		TenderId:     testTenderID,
+		AuthorType:   string(model.AuthorUser),
+		AuthorId:     testAuthor.ID,
+		Price:        &MoneyRequest{Amount: "1500.50", Currency: "RUB"},
+		DeliveryDays: &days,
+		Terms:        "Prepayment 30%",
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d %s", rec.Code, rec.Body)
+	}
+	var resp bidJSON
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Price == nil || resp.Price.Amount != "1500.50" || resp.Price.Currency != "RUB" {
+		t.Errorf("price = %+v, want 1500.50 RUB", resp.Price)
+	}
+	if resp.DeliveryDays == nil || *resp.DeliveryDays != 14 || resp.Terms != "Prepayment 30%" {
+		t.Errorf("delivery days = %v, terms = %q", resp.DeliveryDays, resp.Terms)
+	}
+}
+
+func TestCompareBidsRanksSubmittedBids(t *testing.T) {
+	s := newTestServer(newFakeBidDb())
+	fast, slow := 5, 30
+	expensive := submitBid(t, s, BidRequest{
+		Name: "Fast", Description: "Done in a week", DeliveryDays: &fast,
+		Price: &MoneyRequest{Amount: "2000", Currency: "RUB"},
+	})
+	cheap := submitBid(t, s, BidRequest{
+		Name: "Cheap", Description: "Done in a month", DeliveryDays: &slow,
+		Price: &MoneyRequest{Amount: "900.99", Currency: "RUB"},
+	})
+
+	tests := []struct {
+		sortBy string
+		want   []string
+	}{
+		{sortBy: compareByPrice, want: []string{cheap, expensive}},
+		{sortBy: compareByDeliveryTime, want: []string{expensive, cheap}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			rec := serve(t, s, http.MethodGet,
+				"/api/tenders/"+testTenderID+"/bids/compare?username=responsible&sort_by="+tt.sortBy, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d %s", rec.Code, rec.Body)
+			}
+			var resp []bidJSON
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for i, bid := range resp {
+				got = append(got, bid.ID)
+				if bid.Rank != i+1 || bid.PriceRank == nil || bid.DeliveryRank == nil {
+					t.Errorf("bid %s: rank %d, price rank %v, delivery rank %v", bid.Name, bid.Rank, bid.PriceRank,
+						bid.DeliveryRank)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApprovingBidAwardsOnlyItsLot(t *testing.T) {
+	db := newFakeBidDb()
+	db.lots = []*model.Lot{
+		{ID: "00000000-0000-0000-0000-0000000000d1", TenderID: testTenderID, Name: "North", Status: model.LotOpen},
+		{ID: "00000000-0000-0000-0000-0000000000d2", TenderID: testTenderID, Name: "South", Status: model.LotOpen},
+	}
+	north, south := db.lots[0], db.lots[1]
+	s := newTestServer(db)
+	approve := func(bidID string) {
+		t.Helper()
+		rec := serve(t, s, http.MethodPut,
+			"/api/bids/"+bidID+"/submit_decision?decision=Approved&username=responsible", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("approving bid = %d %s", rec.Code, rec.Body)
+		}
+	}
+
+	northBid := submitBid(t, s, BidRequest{Name: "North works", Description: "North", LotId: &north.ID})
+	approve(northBid)
+
+	if north.Status != model.LotAwarded || north.AwardedBidID == nil || *north.AwardedBidID != northBid {
+		t.Errorf("north lot is %s awarded to %v, want Awarded to %s", north.Status, north.AwardedBidID, northBid)
+	}
+	if south.Status != model.LotOpen {
+		t.Errorf("south lot is %s, want Open", south.Status)
+	}
+	if db.tender.Status != model.TenderPublished {
+		t.Fatalf("tender is %s while the south lot is open, want Published", db.tender.Status)
+	}
+	rec := serve(t, s, http.MethodPost, "/api/bids/new", BidRequest{
+		Name: "Late", Description: "North again", TenderId: testTenderID, AuthorType: string(model.AuthorUser),
+		AuthorId: testAuthor.ID, LotId: &north.ID,
+	})
+	if rec.Code != http.StatusConflict {
+		t.Errorf("bid on the awarded lot: status = %d, want 409", rec.Code)
+	}
+
+	approve(submitBid(t, s, BidRequest{Name: "South works", Description: "South", LotId: &south.ID}))
+
+	if south.Status != model.LotAwarded || db.tender.Status != model.TenderClosed {
+		t.Errorf("after the last lot: south lot is %s, tender is %s; want Awarded and Closed",
+			south.Status, db.tender.Status)
+	}
+	var actions []string
+	for _, entry := range db.audit {
+		actions = append(actions, entry.Action)
+	}
+	if !slices.Contains(actions, model.AuditLotStatus) || !slices.Contains(actions, model.AuditTenderCloseLots) {
+		t.Errorf("audit actions = %v, want lot awards and the tender close", actions)
+	}
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"zadanie-6105/database"
+	"zadanie-6105/model"
+)
+
+const NextCursorHeader = "X-Next-Cursor"
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+func encodeCursor(c *database.TenderCursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(token string) (*database.TenderCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c database.TenderCursor
+	if err = json.Unmarshal(raw, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if _, err = uuid.Parse(c.ID); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.SortBy == "" {
+		c.SortBy = database.SortByName
+	}
+	if !database.IsValidTenderSortField(string(c.SortBy)) {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+func cursorMatchesFilter(c *database.TenderCursor, filter *database.TenderFilter) bool {
+	return c.SortBy == filter.SortField() && c.Descending == filter.Descending
+}
+
+func nextTenderCursor(tenders []model.Tender, filter *database.TenderFilter) string {
+	if filter.IsRankedBySearch() || len(tenders) == 0 || len(tenders) < filter.Limit {
+		return ""
+	}
+	sortBy := filter.SortField()
+	last := tenders[len(tenders)-1]
+	return encodeCursor(&database.TenderCursor{
+		SortBy:     sortBy,
+		Descending: filter.Descending,
+		Key:        sortBy.Key(&last),
+		ID:         last.ID,
+	})
+}
@@ -0,0 +1,225 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/samber/lo"
+	"log/slog"
+	"net/http"
+	"zadanie-6105/database"
+	"zadanie-6105/model"
+)
+
+func (s *Server) newInvitation(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	tenderId := mux.Vars(r)["tenderId"]
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUuid(tenderId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	var req InvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Warn("error decoding body", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "error decoding body"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if !validator.ValidateUuid(req.OrganizationId) {
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	tender, ok := s.getTender(w, r, tenderId)
+	if !ok {
+		return
+	}
+	if !s.checkTenderResponsible(w, r, username, tender) {
+		return
+	}
+	if req.OrganizationId == tender.OrganizationID {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "tender organization can not be invited"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	invitation := &model.TenderInvitation{TenderID: tender.ID, OrganizationID: req.OrganizationId, InvitedBy: employee.ID}
+	err := s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if _, err := db.SaveTenderInvitation(r.Context(), invitation); err != nil {
+			return err
+		}
+		return s.audit(db, r, username, invitationAuditEntry(model.AuditInvitationCreate, tender, req.OrganizationId), nil, invitation)
+	})
+	if err != nil {
+		if errors.Is(err, database.ErrOrganizationNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			resp := ErrResponse{Reason: "organization not found"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		if errors.Is(err, database.ErrInvitationExists) {
+			w.WriteHeader(http.StatusConflict)
+			resp := ErrResponse{Reason: "organization is already invited"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		slog.Warn("error saving invitation", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error saving invitation"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := invitationToResponse(invitation)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) tenderInvitations(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	tenderId := mux.Vars(r)["tenderId"]
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUuid(tenderId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	tender, ok := s.getTender(w, r, tenderId)
+	if !ok {
+		return
+	}
+	if !s.checkTenderResponsible(w, r, username, tender) {
+		return
+	}
+	invitations, err := s.db.GetTenderInvitations(r.Context(), tender.ID)
+	if err != nil {
+		slog.Warn("error getting tender invitations", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting invitations"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := lo.Map(invitations, func(invitation model.TenderInvitation, _ int) *InvitationResponse {
+		return invitationToResponse(&invitation)
+	})
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) deleteInvitation(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	tenderId := mux.Vars(r)["tenderId"]
+	organizationId := mux.Vars(r)["organizationId"]
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUuid(tenderId) {
+		return
+	}
+	if !validator.ValidateUuid(organizationId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	tender, ok := s.getTender(w, r, tenderId)
+	if !ok {
+		return
+	}
+	if !s.checkTenderResponsible(w, r, username, tender) {
+		return
+	}
+	err := s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if err := db.DeleteTenderInvitation(r.Context(), tender.ID, organizationId); err != nil {
+			return err
+		}
+		return s.audit(db, r, username, invitationAuditEntry(model.AuditInvitationDelete, tender, organizationId), nil, nil)
+	})
+	if err != nil {
+		if errors.Is(err, database.ErrInvitationNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			resp := ErrResponse{Reason: "invitation not found"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		slog.Warn("error deleting invitation", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error deleting invitation"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkTenderAvailable writes 403 when the employee may not see the tender.
+// Invitations are only looked up for invite-only tenders.
+func (s *Server) checkTenderAvailable(
+	w http.ResponseWriter, r *http.Request, tender *model.Tender, employee *model.Employee,
+) bool {
+	invited := false
+	if tender.Visibility == model.TenderInviteOnly && tender.CreatorID != employee.ID {
+		var err error
+		invited, err = s.db.IsTenderInvitee(r.Context(), tender.ID, tender.OrganizationID, employee.ID)
+		if err != nil {
+			slog.Warn("error checking tender invitation", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			resp := ErrResponse{Reason: "error checking tender invitation"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return false
+		}
+	}
+	if !IsTenderAvailable(tender, employee, invited) {
+		w.WriteHeader(http.StatusForbidden)
+		resp := ErrResponse{Reason: "tender is not available"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return false
+	}
+	return true
+}
+
+func (s *Server) checkTenderResponsible(w http.ResponseWriter, r *http.Request, username string, tender *model.Tender) bool {
+	return s.checkOrganizationResponsible(w, r, username, tender.OrganizationID)
+}
+
+func (s *Server) checkOrganizationResponsible(
+	w http.ResponseWriter, r *http.Request, username string, organizationId string,
+) bool {
+	isEmployeeInOrganization, err := s.db.IsEmployeeInOrganization(r.Context(), username, organizationId)
+	if err != nil {
+		slog.Warn("error checking employee in organization", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error checking employee in organization"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return false
+	}
+	if !isEmployeeInOrganization {
+		w.WriteHeader(http.StatusForbidden)
+		resp := ErrResponse{Reason: "user not in organization. Tender is not available"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return false
+	}
+	return true
+}
+
+// invitationAuditEntry targets the tender; the invited organization is kept in
+// the entity ID as "<tenderId>/<organizationId>".
+func invitationAuditEntry(action string, tender *model.Tender, organizationId string) *model.AuditEntry {
+	return &model.AuditEntry{
+		Action:         action,
+		EntityType:     model.EntityInvitation,
+		EntityID:       tender.ID + "/" + organizationId,
+		OrganizationID: &tender.OrganizationID,
+	}
+}
+
+func invitationToResponse(invitation *model.TenderInvitation) *InvitationResponse {
+	return &InvitationResponse{
+		TenderID:       invitation.TenderID,
+		OrganizationID: invitation.OrganizationID,
+		CreatedAt:      JSONTime(invitation.CreatedAt),
+	}
+}
@@ -0,0 +1,327 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/samber/lo"
+	"log/slog"
+	"net/http"
+	"zadanie-6105/database"
+	"zadanie-6105/model"
+)
+
+func (s *Server) tenderLots(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	tenderId := mux.Vars(r)["tenderId"]
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUuid(tenderId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	tender, ok := s.getTender(w, r, tenderId)
+	if !ok {
+		return
+	}
+	if !s.checkTenderAvailable(w, r, tender, employee) {
+		return
+	}
+	lots, err := s.db.GetTenderLots(r.Context(), tender.ID)
+	if err != nil {
+		slog.Warn("error getting tender lots", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting lots"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := lo.Map(lots, func(lot model.Lot, _ int) *LotResponse {
+		return lotToResponse(&lot)
+	})
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) newLot(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	tenderId := mux.Vars(r)["tenderId"]
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUuid(tenderId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	var req LotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Warn("error decoding body", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "error decoding body"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if !validateLotRequest(w, &req) {
+		return
+	}
+	tender, ok := s.getTender(w, r, tenderId)
+	if !ok {
+		return
+	}
+	if !s.checkTenderResponsible(w, r, username, tender) {
+		return
+	}
+	if tender.Status == model.TenderClosed {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "lots can not be added to a closed tender"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	lot := &model.Lot{
+		TenderID:    tender.ID,
+		Name:        req.Name,
+		Description: req.Description,
+		Budget:      requestToMoney(req.Budget),
+		Quantity:    req.Quantity,
+	}
+	err := s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if _, err := db.SaveLot(r.Context(), lot); err != nil {
+			return err
+		}
+		return s.audit(db, r, username, lotAuditEntry(model.AuditLotCreate, lot, tender), nil, lot)
+	})
+	if err != nil {
+		if errors.Is(err, database.ErrTenderNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			resp := ErrResponse{Reason: "tender not found"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		slog.Warn("error saving lot", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error saving lot"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := lotToResponse(lot)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) editLot(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	lotId := mux.Vars(r)["lotId"]
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUuid(lotId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	var req LotEditRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Warn("error decoding body", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "error decoding body"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	lot, ok := s.getLot(w, r, lotId)
+	if !ok {
+		return
+	}
+	tender, ok := s.getTender(w, r, lot.TenderID)
+	if !ok {
+		return
+	}
+	if !s.checkTenderResponsible(w, r, username, tender) {
+		return
+	}
+	before := *lot
+	if req.Name != "" {
+		lot.Name = req.Name
+	}
+	if req.Description != "" {
+		lot.Description = req.Description
+	}
+	if req.Budget != nil {
+		lot.Budget = requestToMoney(req.Budget)
+	}
+	if req.Quantity != nil {
+		lot.Quantity = *req.Quantity
+	}
+	merged := LotRequest{Name: lot.Name, Description: lot.Description, Budget: req.Budget, Quantity: lot.Quantity}
+	if !validateLotRequest(w, &merged) {
+		return
+	}
+	err := s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if _, err := db.UpdateLot(r.Context(), lot); err != nil {
+			return err
+		}
+		return s.audit(db, r, username, lotAuditEntry(model.AuditLotEdit, lot, tender), &before, lot)
+	})
+	if err != nil {
+		s.writeLotError(w, err, "error updating lot")
+		return
+	}
+	resp := lotToResponse(lot)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+// updateLotStatus awards a lot to one of its published bids or cancels it.
+// Closing the last open lot closes the tender as well.
+func (s *Server) updateLotStatus(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	lotId := mux.Vars(r)["lotId"]
+	username := r.URL.Query().Get("username")
+	status := model.LotStatus(r.URL.Query().Get("status"))
+	bidId := r.URL.Query().Get("bidId")
+	if !validator.ValidateUuid(lotId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	if status != model.LotAwarded && status != model.LotCancelled {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "status must be Awarded or Cancelled"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if (status == model.LotAwarded) != (bidId != "") {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "bidId is required to award a lot and not allowed otherwise"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if bidId != "" && !validator.ValidateUuid(bidId) {
+		return
+	}
+	lot, ok := s.getLot(w, r, lotId)
+	if !ok {
+		return
+	}
+	tender, ok := s.getTender(w, r, lot.TenderID)
+	if !ok {
+		return
+	}
+	if !s.checkTenderResponsible(w, r, username, tender) {
+		return
+	}
+	var awardedBidId *string
+	if status == model.LotAwarded {
+		bid, ok := s.getBid(w, r, bidId)
+		if !ok {
+			return
+		}
+		if bid.Status != model.BidPublished || bid.LotID == nil || *bid.LotID != lot.ID {
+			w.WriteHeader(http.StatusBadRequest)
+			resp := ErrResponse{Reason: "bid is not a published bid for this lot"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		awardedBidId = &bid.ID
+	}
+	before := lot
+	var closedTender *model.Tender
+	err := s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		var err error
+		lot, closedTender, err = db.CloseLot(r.Context(), lot.ID, status, awardedBidId)
+		if err != nil {
+			return err
+		}
+		if err := s.audit(db, r, username, lotAuditEntry(model.AuditLotStatus, lot, tender), before, lot); err != nil {
+			return err
+		}
+		if closedTender == nil {
+			return nil
+		}
+		return s.audit(db, r, username, tenderAuditEntry(model.AuditTenderCloseLots, closedTender), tender, closedTender)
+	})
+	if err != nil {
+		s.writeLotError(w, err, "error updating lot status")
+		return
+	}
+	if closedTender != nil {
+		slog.Info("tender closed after its last lot", "tender_id", closedTender.ID, "version", closedTender.Version)
+	}
+	resp := lotToResponse(lot)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) getLot(w http.ResponseWriter, r *http.Request, lotId string) (*model.Lot, bool) {
+	lot, err := s.db.GetLotByID(r.Context(), lotId)
+	if err != nil {
+		s.writeLotError(w, err, "error getting lot by id")
+		return nil, false
+	}
+	return lot, true
+}
+
+func (s *Server) writeLotError(w http.ResponseWriter, err error, reason string) {
+	switch {
+	case errors.Is(err, database.ErrLotNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		resp := ErrResponse{Reason: "lot not found"}
+		_ = json.NewEncoder(w).Encode(resp)
+	case errors.Is(err, database.ErrLotClosed):
+		w.WriteHeader(http.StatusConflict)
+		resp := ErrResponse{Reason: "lot is already awarded or cancelled"}
+		_ = json.NewEncoder(w).Encode(resp)
+	default:
+		slog.Warn(reason, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: reason}
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func validateLotRequest(w http.ResponseWriter, req *LotRequest) bool {
+	reason := ""
+	switch {
+	case req.Name == "" || len(req.Name) > 100:
+		reason = "name is empty or too long. Max length is 100"
+	case len(req.Description) > 500:
+		reason = "description is too long. Max length is 500"
+	case req.Quantity <= 0:
+		reason = "quantity must be positive"
+	case !IsValidMoney(req.Budget):
+		reason = "budget is not valid"
+	}
+	if reason != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: reason}
+		_ = json.NewEncoder(w).Encode(resp)
+		return false
+	}
+	return true
+}
+
+func lotAuditEntry(action string, lot *model.Lot, tender *model.Tender) *model.AuditEntry {
+	return &model.AuditEntry{
+		Action:         action,
+		EntityType:     model.EntityLot,
+		EntityID:       lot.ID,
+		OrganizationID: &tender.OrganizationID,
+	}
+}
+
+func lotToResponse(lot *model.Lot) *LotResponse {
+	return &LotResponse{
+		ID:           lot.ID,
+		TenderID:     lot.TenderID,
+		Name:         lot.Name,
+		Description:  lot.Description,
+		Budget:       moneyToResponse(lot.Budget),
+		Quantity:     lot.Quantity,
+		Status:       string(lot.Status),
+		AwardedBidID: lot.AwardedBidID,
+		CreatedAt:    JSONTime(lot.CreatedAt),
+	}
+}
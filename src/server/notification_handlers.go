@@ -0,0 +1,254 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/samber/lo"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"zadanie-6105/database"
+	"zadanie-6105/model"
+)
+
+const UnreadCountHeader = "X-Unread-Count"
+
+// myNotifications returns the employee's notifications, newest first. The
+// total number of unread notifications is sent in the X-Unread-Count header.
+func (s *Server) myNotifications(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	username := r.URL.Query().Get("username")
+	ok, limit, offset := validator.ValidatePagination(r.URL.Query().Get("limit"), r.URL.Query().Get("offset"))
+	if !ok {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	unreadOnly := false
+	if unread := r.URL.Query().Get("unread"); unread != "" {
+		var err error
+		if unreadOnly, err = strconv.ParseBool(unread); err != nil {
+			validator.writeBadRequest("unread must be true or false")
+			return
+		}
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	notifications, err := s.db.GetNotifications(r.Context(), limit, offset, employee.ID, unreadOnly)
+	if err != nil {
+		slog.Warn("error getting notifications", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting notifications"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	unreadCount, err := s.db.CountUnreadNotifications(r.Context(), employee.ID)
+	if err != nil {
+		slog.Warn("error counting unread notifications", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting notifications"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	w.Header().Set(UnreadCountHeader, strconv.Itoa(unreadCount))
+	resp := lo.Map(notifications, func(n model.Notification, _ int) *NotificationResponse {
+		return notificationToResponse(&n)
+	})
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) readNotification(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	notificationId := mux.Vars(r)["notificationId"]
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUuid(notificationId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	var notification *model.Notification
+	err := s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		var err error
+		if notification, err = db.MarkNotificationRead(r.Context(), notificationId, employee.ID); err != nil {
+			return err
+		}
+		entry := notificationAuditEntry(model.AuditNotificationRead, notification.ID)
+		return s.audit(db, r, username, entry, nil, notification)
+	})
+	if err != nil {
+		if errors.Is(err, database.ErrNotificationNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			resp := ErrResponse{Reason: "notification not found"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		slog.Warn("error marking notification read", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error marking notification read"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := notificationToResponse(notification)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) readAllNotifications(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	var updated int
+	err := s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		var err error
+		if updated, err = db.MarkAllNotificationsRead(r.Context(), employee.ID); err != nil {
+			return err
+		}
+		// The entry is keyed by the employee, as it covers all of their notifications.
+		entry := notificationAuditEntry(model.AuditNotificationReadAll, employee.ID)
+		return s.audit(db, r, username, entry, nil, NotificationsReadResponse{Updated: updated})
+	})
+	if err != nil {
+		slog.Warn("error marking notifications read", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error marking notifications read"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := NotificationsReadResponse{Updated: updated}
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) emailPreferences(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	prefs, err := s.db.GetEmailPreferences(r.Context(), employee.ID)
+	if err != nil {
+		slog.Warn("error getting email preferences", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting email preferences"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := emailPreferencesToResponse(prefs)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+// editEmailPreferences updates the given fields. An empty email removes the
+// address and with it all notification emails.
+func (s *Server) editEmailPreferences(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	var req EmailPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Warn("error decoding body", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "error decoding body"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if req.Email != nil && *req.Email != "" && !IsValidEmail(*req.Email) {
+		validator.writeBadRequest("email is not valid")
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	prefs, err := s.db.GetEmailPreferences(r.Context(), employee.ID)
+	if err != nil {
+		slog.Warn("error getting email preferences", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting email preferences"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	before := *prefs
+	if req.Email != nil {
+		prefs.Email = nullableString(*req.Email)
+	}
+	if req.BidSubmitted != nil {
+		prefs.BidSubmitted = *req.BidSubmitted
+	}
+	if req.BidDecided != nil {
+		prefs.BidDecided = *req.BidDecided
+	}
+	err = s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if _, err := db.SaveEmailPreferences(r.Context(), prefs); err != nil {
+			return err
+		}
+		entry := &model.AuditEntry{
+			Action:     model.AuditEmailPreferencesEdit,
+			EntityType: model.EntityEmailPreferences,
+			EntityID:   employee.ID,
+		}
+		return s.audit(db, r, username, entry, &before, prefs)
+	})
+	if err != nil {
+		slog.Warn("error saving email preferences", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error saving email preferences"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := emailPreferencesToResponse(prefs)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func emailPreferencesToResponse(prefs *model.EmailPreferences) *EmailPreferencesResponse {
+	return &EmailPreferencesResponse{
+		Email:        prefs.Email,
+		BidSubmitted: prefs.BidSubmitted,
+		BidDecided:   prefs.BidDecided,
+	}
+}
+
+func notificationAuditEntry(action, entityId string) *model.AuditEntry {
+	return &model.AuditEntry{
+		Action:     action,
+		EntityType: model.EntityNotification,
+		EntityID:   entityId,
+	}
+}
+
+func notificationToResponse(n *model.Notification) *NotificationResponse {
+	return &NotificationResponse{
+		ID:            n.ID,
+		Type:          string(n.Type),
+		TenderID:      n.TenderID,
+		TenderVersion: n.TenderVersion,
+		SavedSearchID: n.SavedSearchID,
+		Title:         n.Title,
+		Read:          n.ReadAt != nil,
+		CreatedAt:     JSONTime(n.CreatedAt),
+		ReadAt:        jsonTime(n.ReadAt),
+	}
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"zadanie-6105/database"
+	"zadanie-6105/model"
+)
+
+// fakeEmailDb adds the email preferences of testAuthor to fakeBidDb.
+type fakeEmailDb struct {
+	*fakeBidDb
+	prefs model.EmailPreferences
+}
+
+func (db *fakeEmailDb) InTx(_ context.Context, fn func(db database.DbConnector) error) error {
+	return fn(db)
+}
+
+func (db *fakeEmailDb) GetEmailPreferences(_ context.Context, employeeID string) (*model.EmailPreferences, error) {
+	prefs := db.prefs
+	prefs.EmployeeID = employeeID
+	return &prefs, nil
+}
+
+func (db *fakeEmailDb) SaveEmailPreferences(_ context.Context, prefs *model.EmailPreferences) (*model.EmailPreferences, error) {
+	db.prefs = *prefs
+	return prefs, nil
+}
+
+func TestEditEmailPreferencesValidatesAndAuditsEmail(t *testing.T) {
+	db := &fakeEmailDb{fakeBidDb: newFakeBidDb(), prefs: model.EmailPreferences{BidSubmitted: true, BidDecided: true}}
+	s := newTestServer(db)
+	for _, email := range []string{"author", "Author <author@example.com>", "author@example.com,other@example.com"} {
+		rec := serve(t, s, http.MethodPut, "/api/notifications/settings?username=author", EmailPreferencesRequest{Email: &email})
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("email %q: status = %d, want %d", email, rec.Code, http.StatusBadRequest)
+		}
+	}
+	if db.prefs.Email != nil || len(db.audit) != 0 {
+		t.Fatalf("invalid emails were saved: %v, %d audit entries", db.prefs.Email, len(db.audit))
+	}
+
+	email := "author@example.com"
+	rec := serve(t, s, http.MethodPut, "/api/notifications/settings?username=author", EmailPreferencesRequest{Email: &email})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	if db.prefs.Email == nil || *db.prefs.Email != email {
+		t.Errorf("saved email = %v, want %q", db.prefs.Email, email)
+	}
+	if len(db.audit) != 1 || db.audit[0].Action != model.AuditEmailPreferencesEdit || db.audit[0].EntityID != testAuthor.ID {
+		t.Errorf("audit = %+v, want one %s entry for %s", db.audit, model.AuditEmailPreferencesEdit, testAuthor.ID)
+	}
+}
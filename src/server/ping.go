@@ -1,10 +1,22 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	log "log/slog"
 	"net/http"
+	"time"
+	"zadanie-6105/database"
 )
 
+const (
+	readyCheckTimeout = 2 * time.Second
+	checkOK           = "ok"
+	checkFailed       = "failed"
+)
+
+// ping is the liveness probe: it only tells that the process serves requests.
 func (s *Server) ping(w http.ResponseWriter, _ *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	_, err := w.Write([]byte("ok"))
@@ -12,3 +24,78 @@ func (s *Server) ping(w http.ResponseWriter, _ *http.Request) {
 		log.Warn("error during write http response", "error", err)
 	}
 }
+
+// ready is the readiness probe. It checks the database connection, the schema
+// version and the background workers, and answers 503 when any check fails or
+// the instance is shutting down.
+func (s *Server) ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyCheckTimeout)
+	defer cancel()
+	checks := []ReadinessCheckResponse{s.checkShutdown(), s.checkDatabase(ctx), s.checkSchema(ctx)}
+	checks = append(checks, s.checkWorkers()...)
+
+	resp := ReadinessResponse{Status: "ready"}
+	code := http.StatusOK
+	for _, check := range checks {
+		if check.Status != checkOK {
+			resp.Status = "not ready"
+			code = http.StatusServiceUnavailable
+		}
+	}
+	resp.Checks = checks
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) checkShutdown() ReadinessCheckResponse {
+	check := ReadinessCheckResponse{Name: "shutdown", Status: checkOK}
+	if s.health.Stopping() {
+		check.Status = checkFailed
+		check.Reason = "instance is shutting down"
+	}
+	return check
+}
+
+func (s *Server) checkDatabase(ctx context.Context) ReadinessCheckResponse {
+	check := ReadinessCheckResponse{Name: "database", Status: checkOK}
+	if err := s.db.Ping(ctx); err != nil {
+		log.Warn("readiness: database ping failed", "error", err)
+		check.Status = checkFailed
+		check.Reason = "database is not reachable"
+	}
+	return check
+}
+
+// checkSchema accepts newer schema versions, so that instances of the
+// previous release keep serving while a migrated database is rolled out.
+func (s *Server) checkSchema(ctx context.Context) ReadinessCheckResponse {
+	check := ReadinessCheckResponse{Name: "schema", Status: checkOK}
+	version, err := s.db.GetSchemaVersion(ctx)
+	switch {
+	case err != nil:
+		check.Status = checkFailed
+		check.Reason = "error getting schema version"
+	case version < database.SchemaVersion:
+		check.Status = checkFailed
+		check.Reason = fmt.Sprintf("schema version is %d, expected at least %d", version, database.SchemaVersion)
+	}
+	return check
+}
+
+func (s *Server) checkWorkers() []ReadinessCheckResponse {
+	workers := s.health.Workers(time.Now())
+	checks := make([]ReadinessCheckResponse, 0, len(workers))
+	for _, worker := range workers {
+		check := ReadinessCheckResponse{Name: "worker:" + worker.Name, Status: checkOK}
+		if !worker.LastBeat.IsZero() {
+			check.LastBeat = jsonTime(&worker.LastBeat)
+		}
+		if !worker.Healthy {
+			check.Status = checkFailed
+			check.Reason = "worker has not run for too long"
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
@@ -0,0 +1,269 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"zadanie-6105/database"
+	"zadanie-6105/model"
+)
+
+const (
+	MaxQuestionLength = 1000
+	MaxAnswerLength   = 2000
+)
+
+func (s *Server) newQuestion(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	tenderId := mux.Vars(r)["tenderId"]
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUuid(tenderId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	var req QuestionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Warn("error decoding body", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "error decoding body"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if req.Text == "" || len(req.Text) > MaxQuestionLength {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "question text is empty or too long. Max length is " + strconv.Itoa(MaxQuestionLength)}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	tender, ok := s.getTender(w, r, tenderId)
+	if !ok {
+		return
+	}
+	if tender.Status != model.TenderPublished {
+		w.WriteHeader(http.StatusForbidden)
+		resp := ErrResponse{Reason: "questions can be asked only on published tenders"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if !s.checkTenderAvailable(w, r, tender, employee) {
+		return
+	}
+	question := &model.Question{TenderID: tender.ID, AuthorID: employee.ID, Text: req.Text}
+	err := s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if _, err := db.SaveQuestion(r.Context(), question); err != nil {
+			return err
+		}
+		return s.audit(db, r, username, questionAuditEntry(model.AuditQuestionCreate, question, tender), nil, question)
+	})
+	if err != nil {
+		slog.Warn("error saving question", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error saving question"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := questionToResponse(question, true)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) answerQuestion(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	questionId := mux.Vars(r)["questionId"]
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUuid(questionId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	var req AnswerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Warn("error decoding body", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "error decoding body"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if req.Answer == "" || len(req.Answer) > MaxAnswerLength {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "answer is empty or too long. Max length is " + strconv.Itoa(MaxAnswerLength)}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	question, err := s.db.GetQuestionByID(r.Context(), questionId)
+	if err != nil {
+		if errors.Is(err, database.ErrQuestionNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			resp := ErrResponse{Reason: "question not found"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		slog.Warn("error getting question by id", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting question by id"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	tender, ok := s.getTender(w, r, question.TenderID)
+	if !ok {
+		return
+	}
+	isEmployeeInOrganization, err := s.db.IsEmployeeInOrganization(r.Context(), username, tender.OrganizationID)
+	if err != nil {
+		slog.Warn("error checking employee in organization", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error checking employee in organization"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if !isEmployeeInOrganization {
+		w.WriteHeader(http.StatusForbidden)
+		resp := ErrResponse{Reason: "only organization responsibles can answer questions"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	before := *question
+	question.Answer = &req.Answer
+	question.AnsweredBy = &employee.ID
+	question.AnswerPublic = req.Public
+	err = s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if _, err := db.AnswerQuestion(r.Context(), question); err != nil {
+			return err
+		}
+		return s.audit(db, r, username, questionAuditEntry(model.AuditQuestionAnswer, question, tender), &before, question)
+	})
+	if err != nil {
+		if errors.Is(err, database.ErrQuestionNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			resp := ErrResponse{Reason: "question not found"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		slog.Warn("error answering question", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error answering question"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := questionToResponse(question, true)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) tenderQuestions(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	tenderId := mux.Vars(r)["tenderId"]
+	username := r.URL.Query().Get("username")
+	ok, limit, offset := validator.ValidatePagination(r.URL.Query().Get("limit"), r.URL.Query().Get("offset"))
+	if !ok {
+		return
+	}
+	if !validator.ValidateUuid(tenderId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	tender, ok := s.getTender(w, r, tenderId)
+	if !ok {
+		return
+	}
+	isResponsible, err := s.db.IsEmployeeInOrganization(r.Context(), username, tender.OrganizationID)
+	if err != nil {
+		slog.Warn("error checking employee in organization", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error checking employee in organization"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if !isResponsible && !s.checkTenderAvailable(w, r, tender, employee) {
+		return
+	}
+	questions, err := s.db.GetTenderQuestions(r.Context(), limit, offset, tender.ID, employee.ID, isResponsible)
+	if err != nil {
+		slog.Warn("error getting tender questions", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting questions"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := make([]*QuestionResponse, 0, len(questions))
+	for i := range questions {
+		resp = append(resp, questionToResponse(&questions[i], isResponsible || questions[i].AuthorID == employee.ID))
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) myQuestions(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	username := r.URL.Query().Get("username")
+	ok, limit, offset := validator.ValidatePagination(r.URL.Query().Get("limit"), r.URL.Query().Get("offset"))
+	if !ok {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	questions, err := s.db.GetQuestionsByAuthorID(r.Context(), limit, offset, employee.ID)
+	if err != nil {
+		slog.Warn("error getting questions by author id", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting questions"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := make([]*QuestionResponse, 0, len(questions))
+	for i := range questions {
+		resp = append(resp, questionToResponse(&questions[i], true))
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func questionAuditEntry(action string, question *model.Question, tender *model.Tender) *model.AuditEntry {
+	return &model.AuditEntry{
+		Action:         action,
+		EntityType:     model.EntityQuestion,
+		EntityID:       question.ID,
+		OrganizationID: &tender.OrganizationID,
+	}
+}
+
+func questionToResponse(q *model.Question, withAuthor bool) *QuestionResponse {
+	resp := &QuestionResponse{
+		ID:           q.ID,
+		TenderID:     q.TenderID,
+		Text:         q.Text,
+		CreatedAt:    JSONTime(q.CreatedAt),
+		Answer:       q.Answer,
+		AnsweredAt:   jsonTime(q.AnsweredAt),
+		AnswerPublic: q.AnswerPublic,
+	}
+	if withAuthor {
+		resp.AuthorID = q.AuthorID
+	}
+	return resp
+}
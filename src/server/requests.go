@@ -1,15 +1,120 @@
 package server
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type TenderRequest struct {
-	Name            string `json:"name"`
-	Description     string `json:"description"`
-	ServiceType     string `json:"serviceType"`
-	OrganizationId  string `json:"organizationId"`
-	CreatorUsername string `json:"creatorUsername"`
+	Name            string        `json:"name"`
+	Description     string        `json:"description"`
+	ServiceType     string        `json:"serviceType"`
+	OrganizationId  string        `json:"organizationId"`
+	CreatorUsername string        `json:"creatorUsername"`
+	Deadline        *time.Time    `json:"deadline,omitempty"`
+	PublishAt       *time.Time    `json:"publishAt,omitempty"`
+	Budget          *MoneyRequest `json:"budget,omitempty"`
+	Visibility      string        `json:"visibility,omitempty"`
 }
 
 type TenderEditRequest struct {
-	Name        string `json:"name,omitempty"`
-	Description string `json:"description,omitempty"`
-	ServiceType string `json:"serviceType,omitempty"`
+	Name        string        `json:"name,omitempty"`
+	Description string        `json:"description,omitempty"`
+	ServiceType string        `json:"serviceType,omitempty"`
+	Deadline    *time.Time    `json:"deadline,omitempty"`
+	PublishAt   *time.Time    `json:"publishAt,omitempty"`
+	Budget      *MoneyRequest `json:"budget,omitempty"`
+	Visibility  string        `json:"visibility,omitempty"`
+}
+
+type TenderCloneRequest struct {
+	Name           string `json:"name,omitempty"`
+	OrganizationId string `json:"organizationId,omitempty"`
+}
+
+type MoneyRequest struct {
+	Amount   json.Number `json:"amount"`
+	Currency string      `json:"currency"`
+}
+
+type ServiceTypeRequest struct {
+	Name       *string `json:"name,omitempty"`
+	Parent     *string `json:"parent,omitempty"`
+	Deprecated *bool   `json:"deprecated,omitempty"`
+}
+
+type QuestionRequest struct {
+	Text string `json:"text"`
+}
+
+type AnswerRequest struct {
+	Answer string `json:"answer"`
+	Public bool   `json:"public"`
+}
+
+type InvitationRequest struct {
+	OrganizationId string `json:"organizationId"`
+}
+
+type LotRequest struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Budget      *MoneyRequest `json:"budget,omitempty"`
+	Quantity    int           `json:"quantity"`
+}
+
+type LotEditRequest struct {
+	Name        string        `json:"name,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Budget      *MoneyRequest `json:"budget,omitempty"`
+	Quantity    *int          `json:"quantity,omitempty"`
+}
+
+type TemplateRequest struct {
+	Name              string        `json:"name,omitempty"`
+	TenderName        string        `json:"tenderName,omitempty"`
+	TenderDescription string        `json:"tenderDescription,omitempty"`
+	ServiceType       string        `json:"serviceType,omitempty"`
+	Visibility        string        `json:"visibility,omitempty"`
+	Budget            *MoneyRequest `json:"budget,omitempty"`
+}
+
+type TenderFromTemplateRequest struct {
+	Values    map[string]string `json:"values"`
+	Deadline  *time.Time        `json:"deadline,omitempty"`
+	PublishAt *time.Time        `json:"publishAt,omitempty"`
+}
+
+type WebhookRequest struct {
+	URL        string   `json:"url,omitempty"`
+	EventTypes []string `json:"eventTypes,omitempty"`
+	Secret     string   `json:"secret,omitempty"`
+	Active     *bool    `json:"active,omitempty"`
+}
+
+type SavedSearchRequest struct {
+	Name           string   `json:"name"`
+	ServiceTypes   []string `json:"serviceTypes,omitempty"`
+	Keywords       string   `json:"keywords,omitempty"`
+	BudgetMin      string   `json:"budgetMin,omitempty"`
+	BudgetMax      string   `json:"budgetMax,omitempty"`
+	BudgetCurrency string   `json:"budgetCurrency,omitempty"`
+}
+
+type EmailPreferencesRequest struct {
+	Email        *string `json:"email,omitempty"`
+	BidSubmitted *bool   `json:"bidSubmitted,omitempty"`
+	BidDecided   *bool   `json:"bidDecided,omitempty"`
+}
+
+type BidRequest struct {
+	Name         string        `json:"name"`
+	Description  string        `json:"description"`
+	TenderId     string        `json:"tenderId"`
+	AuthorType   string        `json:"authorType"`
+	AuthorId     string        `json:"authorId"`
+	Price        *MoneyRequest `json:"price,omitempty"`
+	DeliveryDays *int          `json:"deliveryDays,omitempty"`
+	Terms        string        `json:"terms,omitempty"`
+	LotId        *string       `json:"lotId,omitempty"`
 }
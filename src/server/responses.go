@@ -1,6 +1,7 @@
 package server
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -16,12 +17,224 @@ type ErrResponse struct {
 	Reason string `json:"reason"`
 }
 
+type MissingPlaceholdersResponse struct {
+	Reason  string   `json:"reason"`
+	Missing []string `json:"missing"`
+}
+
 type TenderResponse struct {
-	ID          string   `json:"id"`
-	Name        string   `json:"name"`
-	Description string   `json:"description"`
-	Status      string   `json:"status"`
-	ServiceType string   `json:"serviceType"`
-	Version     int      `json:"version"`
-	CreatedAt   JSONTime `json:"createdAt"`
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Status      string         `json:"status"`
+	ServiceType string         `json:"serviceType"`
+	Version     int            `json:"version"`
+	CreatedAt   JSONTime       `json:"createdAt"`
+	Deadline    *JSONTime      `json:"deadline,omitempty"`
+	PublishAt   *JSONTime      `json:"publishAt,omitempty"`
+	Budget      *MoneyResponse `json:"budget,omitempty"`
+	Visibility  string         `json:"visibility"`
+}
+
+type MoneyResponse struct {
+	Amount   json.Number `json:"amount"`
+	Currency string      `json:"currency"`
+}
+
+type BidResponse struct {
+	ID           string         `json:"id"`
+	Name         string         `json:"name"`
+	Description  string         `json:"description"`
+	Status       string         `json:"status"`
+	TenderID     string         `json:"tenderId"`
+	AuthorType   string         `json:"authorType"`
+	AuthorID     string         `json:"authorId"`
+	Version      int            `json:"version"`
+	CreatedAt    JSONTime       `json:"createdAt"`
+	Price        *MoneyResponse `json:"price,omitempty"`
+	DeliveryDays *int           `json:"deliveryDays,omitempty"`
+	Terms        string         `json:"terms,omitempty"`
+	LotID        *string        `json:"lotId,omitempty"`
+}
+
+type BidComparisonResponse struct {
+	Rank         int            `json:"rank"`
+	PriceRank    *int           `json:"priceRank,omitempty"`
+	DeliveryRank *int           `json:"deliveryRank,omitempty"`
+	ID           string         `json:"id"`
+	Name         string         `json:"name"`
+	Description  string         `json:"description"`
+	AuthorType   string         `json:"authorType"`
+	AuthorID     string         `json:"authorId"`
+	Version      int            `json:"version"`
+	CreatedAt    JSONTime       `json:"createdAt"`
+	Price        *MoneyResponse `json:"price,omitempty"`
+	DeliveryDays *int           `json:"deliveryDays,omitempty"`
+	Terms        string         `json:"terms,omitempty"`
+	LotID        *string        `json:"lotId,omitempty"`
+}
+
+type ServiceTypeResponse struct {
+	Name       string   `json:"name"`
+	Parent     *string  `json:"parent,omitempty"`
+	Deprecated bool     `json:"deprecated"`
+	CreatedAt  JSONTime `json:"createdAt"`
+}
+
+type AttachmentResponse struct {
+	ID            string   `json:"id"`
+	TenderID      string   `json:"tenderId"`
+	TenderVersion int      `json:"tenderVersion"`
+	BidID         *string  `json:"bidId,omitempty"`
+	BidVersion    *int     `json:"bidVersion,omitempty"`
+	FileName      string   `json:"fileName"`
+	ContentType   string   `json:"contentType"`
+	Size          int64    `json:"size"`
+	SHA256        string   `json:"sha256"`
+	DownloadURL   string   `json:"downloadUrl"`
+	CreatedAt     JSONTime `json:"createdAt"`
+}
+
+type QuestionResponse struct {
+	ID           string    `json:"id"`
+	TenderID     string    `json:"tenderId"`
+	AuthorID     string    `json:"authorId,omitempty"`
+	Text         string    `json:"text"`
+	CreatedAt    JSONTime  `json:"createdAt"`
+	Answer       *string   `json:"answer,omitempty"`
+	AnsweredAt   *JSONTime `json:"answeredAt,omitempty"`
+	AnswerPublic bool      `json:"answerPublic"`
+}
+
+type InvitationResponse struct {
+	TenderID       string   `json:"tenderId"`
+	OrganizationID string   `json:"organizationId"`
+	CreatedAt      JSONTime `json:"createdAt"`
+}
+
+type LotResponse struct {
+	ID           string         `json:"id"`
+	TenderID     string         `json:"tenderId"`
+	Name         string         `json:"name"`
+	Description  string         `json:"description"`
+	Budget       *MoneyResponse `json:"budget,omitempty"`
+	Quantity     int            `json:"quantity"`
+	Status       string         `json:"status"`
+	AwardedBidID *string        `json:"awardedBidId,omitempty"`
+	CreatedAt    JSONTime       `json:"createdAt"`
+}
+
+type TemplateResponse struct {
+	ID                string         `json:"id"`
+	OrganizationID    string         `json:"organizationId"`
+	Name              string         `json:"name"`
+	TenderName        string         `json:"tenderName"`
+	TenderDescription string         `json:"tenderDescription"`
+	ServiceType       string         `json:"serviceType"`
+	Visibility        string         `json:"visibility"`
+	Budget            *MoneyResponse `json:"budget,omitempty"`
+	Placeholders      []string       `json:"placeholders"`
+	CreatedAt         JSONTime       `json:"createdAt"`
+	UpdatedAt         JSONTime       `json:"updatedAt"`
+}
+
+type AuditEntryResponse struct {
+	ID             int64           `json:"id"`
+	OccurredAt     JSONTime        `json:"occurredAt"`
+	ActorID        *string         `json:"actorId,omitempty"`
+	ActorUsername  *string         `json:"actorUsername,omitempty"`
+	Action         string          `json:"action"`
+	EntityType     string          `json:"entityType"`
+	EntityID       string          `json:"entityId"`
+	EntityVersion  *int            `json:"entityVersion,omitempty"`
+	OrganizationID *string         `json:"organizationId,omitempty"`
+	RequestID      *string         `json:"requestId,omitempty"`
+	ClientIP       *string         `json:"clientIp,omitempty"`
+	Before         json.RawMessage `json:"before,omitempty"`
+	After          json.RawMessage `json:"after,omitempty"`
+}
+
+type ChainReportResponse struct {
+	Chain   string              `json:"chain"`
+	Checked int                 `json:"checked"`
+	Valid   bool                `json:"valid"`
+	Broken  *BrokenLinkResponse `json:"broken,omitempty"`
+}
+
+type BrokenLinkResponse struct {
+	ID     string `json:"id"`
+	Seq    int64  `json:"seq"`
+	Reason string `json:"reason"`
+}
+
+type WebhookResponse struct {
+	ID             string   `json:"id"`
+	OrganizationID string   `json:"organizationId"`
+	URL            string   `json:"url"`
+	EventTypes     []string `json:"eventTypes"`
+	Active         bool     `json:"active"`
+	Secret         string   `json:"secret,omitempty"`
+	CreatedAt      JSONTime `json:"createdAt"`
+	UpdatedAt      JSONTime `json:"updatedAt"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID             string          `json:"id"`
+	WebhookID      string          `json:"webhookId"`
+	EventID        int64           `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	RedeliveryOf   *string         `json:"redeliveryOf,omitempty"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *JSONTime       `json:"nextAttemptAt,omitempty"`
+	LastStatusCode *int            `json:"lastStatusCode,omitempty"`
+	LastError      *string         `json:"lastError,omitempty"`
+	CreatedAt      JSONTime        `json:"createdAt"`
+	DeliveredAt    *JSONTime       `json:"deliveredAt,omitempty"`
+}
+
+type SavedSearchResponse struct {
+	ID             string   `json:"id"`
+	Name           string   `json:"name"`
+	ServiceTypes   []string `json:"serviceTypes"`
+	Keywords       *string  `json:"keywords,omitempty"`
+	BudgetMin      *string  `json:"budgetMin,omitempty"`
+	BudgetMax      *string  `json:"budgetMax,omitempty"`
+	BudgetCurrency *string  `json:"budgetCurrency,omitempty"`
+	CreatedAt      JSONTime `json:"createdAt"`
+}
+
+type NotificationResponse struct {
+	ID            string    `json:"id"`
+	Type          string    `json:"type"`
+	TenderID      string    `json:"tenderId"`
+	TenderVersion int       `json:"tenderVersion"`
+	SavedSearchID *string   `json:"savedSearchId,omitempty"`
+	Title         string    `json:"title"`
+	Read          bool      `json:"read"`
+	CreatedAt     JSONTime  `json:"createdAt"`
+	ReadAt        *JSONTime `json:"readAt,omitempty"`
+}
+
+type NotificationsReadResponse struct {
+	Updated int `json:"updated"`
+}
+
+type EmailPreferencesResponse struct {
+	Email        *string `json:"email,omitempty"`
+	BidSubmitted bool    `json:"bidSubmitted"`
+	BidDecided   bool    `json:"bidDecided"`
+}
+
+type ReadinessResponse struct {
+	Status string                   `json:"status"`
+	Checks []ReadinessCheckResponse `json:"checks"`
+}
+
+type ReadinessCheckResponse struct {
+	Name     string    `json:"name"`
+	Status   string    `json:"status"`
+	Reason   string    `json:"reason,omitempty"`
+	LastBeat *JSONTime `json:"lastBeat,omitempty"`
 }
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"log/slog"
+	"net/http"
+	"zadanie-6105/database"
+	"zadanie-6105/model"
+)
+
+func (s *Server) scheduledTenders(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	username := r.URL.Query().Get("username")
+	ok, limit, offset := validator.ValidatePagination(r.URL.Query().Get("limit"), r.URL.Query().Get("offset"))
+	if !ok {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	tenders, err := s.db.GetTenders(r.Context(), &database.TenderFilter{
+		Limit:     limit,
+		Offset:    offset,
+		ManagedBy: employee.ID,
+		Scheduled: true,
+		Statuses:  []model.TenderStatus{model.TenderCreated},
+	})
+	if err != nil {
+		slog.Warn("error getting scheduled tenders", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting tenders"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := tendersToResponse(tenders)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) cancelTenderSchedule(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	tenderId := mux.Vars(r)["tenderId"]
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUuid(tenderId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	tender, err := s.db.GetTenderByID(r.Context(), tenderId)
+	if err != nil {
+		if errors.Is(err, database.ErrTenderNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			resp := ErrResponse{Reason: "tender not found"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		slog.Warn("error getting tender by id", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting tender by id"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	isEmployeeInOrganization, err := s.db.IsEmployeeInOrganization(r.Context(), employee.Username, tender.OrganizationID)
+	if err != nil {
+		slog.Warn("error checking employee in organization", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error checking employee in organization"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if !isEmployeeInOrganization {
+		w.WriteHeader(http.StatusForbidden)
+		resp := ErrResponse{Reason: "user not in organization. Tender is not available"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if tender.Status != model.TenderCreated || tender.PublishAt == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "tender is not scheduled for publication"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	before := *tender
+	tender.PublishAt = nil
+	err = s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if _, err := db.UpdateTender(r.Context(), tender, model.EventTenderEdited); err != nil {
+			return err
+		}
+		return s.audit(db, r, username, tenderAuditEntry(model.AuditTenderScheduleCancel, tender), &before, tender)
+	})
+	if err != nil {
+		slog.Warn("error updating tender", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error updating tender"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := tenderToResponse(tender)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
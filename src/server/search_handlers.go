@@ -0,0 +1,224 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/samber/lo"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strconv"
+	"zadanie-6105/database"
+	"zadanie-6105/model"
+)
+
+const (
+	MaxSavedSearchNameLength = 100
+	MaxSavedSearches         = 20
+)
+
+func (s *Server) mySavedSearches(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	username := r.URL.Query().Get("username")
+	ok, limit, offset := validator.ValidatePagination(r.URL.Query().Get("limit"), r.URL.Query().Get("offset"))
+	if !ok {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	searches, err := s.db.GetEmployeeSavedSearches(r.Context(), limit, offset, employee.ID)
+	if err != nil {
+		slog.Warn("error getting saved searches", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting saved searches"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := lo.Map(searches, func(search model.SavedSearch, _ int) *SavedSearchResponse {
+		return savedSearchToResponse(&search)
+	})
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+// newSavedSearch stores a filter that is matched against tenders when they
+// are published. Matches show up in the employee's notifications.
+func (s *Server) newSavedSearch(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	var req SavedSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Warn("error decoding body", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "error decoding body"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if !s.validateSavedSearchRequest(w, r, &req) {
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	search := &model.SavedSearch{
+		EmployeeID:     employee.ID,
+		Name:           req.Name,
+		ServiceTypes:   lo.Uniq(req.ServiceTypes),
+		Keywords:       nullableString(req.Keywords),
+		BudgetMin:      nullableString(req.BudgetMin),
+		BudgetMax:      nullableString(req.BudgetMax),
+		BudgetCurrency: nullableString(req.BudgetCurrency),
+	}
+	if search.ServiceTypes == nil {
+		search.ServiceTypes = []string{}
+	}
+	err := s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if _, err := db.SaveSavedSearch(r.Context(), search, MaxSavedSearches); err != nil {
+			return err
+		}
+		return s.audit(db, r, username, savedSearchAuditEntry(model.AuditSavedSearchCreate, search), nil, search)
+	})
+	if err != nil {
+		s.writeSavedSearchError(w, err, "error saving saved search")
+		return
+	}
+	resp := savedSearchToResponse(search)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) deleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	searchId := mux.Vars(r)["searchId"]
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUuid(searchId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	search, err := s.db.GetSavedSearchByID(r.Context(), searchId)
+	if err == nil && search.EmployeeID != employee.ID {
+		err = database.ErrSavedSearchNotFound
+	}
+	if err != nil {
+		s.writeSavedSearchError(w, err, "error getting saved search by id")
+		return
+	}
+	err = s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if err := db.DeleteSavedSearch(r.Context(), search.ID); err != nil {
+			return err
+		}
+		return s.audit(db, r, username, savedSearchAuditEntry(model.AuditSavedSearchDelete, search), search, nil)
+	})
+	if err != nil {
+		s.writeSavedSearchError(w, err, "error deleting saved search")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) writeSavedSearchError(w http.ResponseWriter, err error, reason string) {
+	switch {
+	case errors.Is(err, database.ErrSavedSearchNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		resp := ErrResponse{Reason: "saved search not found"}
+		_ = json.NewEncoder(w).Encode(resp)
+	case errors.Is(err, database.ErrSavedSearchExists):
+		w.WriteHeader(http.StatusConflict)
+		resp := ErrResponse{Reason: "saved search with same name already exists"}
+		_ = json.NewEncoder(w).Encode(resp)
+	case errors.Is(err, database.ErrSavedSearchLimit):
+		w.WriteHeader(http.StatusConflict)
+		resp := ErrResponse{Reason: "too many saved searches. Max is " + strconv.Itoa(MaxSavedSearches)}
+		_ = json.NewEncoder(w).Encode(resp)
+	default:
+		slog.Warn(reason, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: reason}
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func (s *Server) validateSavedSearchRequest(w http.ResponseWriter, r *http.Request, req *SavedSearchRequest) bool {
+	reason := ""
+	switch {
+	case req.Name == "" || len(req.Name) > MaxSavedSearchNameLength:
+		reason = "name is empty or too long. Max length is 100"
+	case len(req.Keywords) > MaxSearchQueryLength:
+		reason = "keywords are too long. Max length is " + strconv.Itoa(MaxSearchQueryLength)
+	case req.BudgetMin != "" && !IsValidAmount(req.BudgetMin):
+		reason = "budgetMin is not a valid amount"
+	case req.BudgetMax != "" && !IsValidAmount(req.BudgetMax):
+		reason = "budgetMax is not a valid amount"
+	case req.BudgetMin != "" && req.BudgetMax != "" && compareAmounts(req.BudgetMin, req.BudgetMax) > 0:
+		reason = "budgetMin must not be greater than budgetMax"
+	case req.BudgetCurrency != "" && !IsValidCurrency(req.BudgetCurrency):
+		reason = "budgetCurrency is not a valid ISO 4217 code"
+	// Amounts in different currencies are not comparable.
+	case (req.BudgetMin != "" || req.BudgetMax != "") && req.BudgetCurrency == "":
+		reason = "budgetCurrency is required with budgetMin or budgetMax"
+	}
+	if reason != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: reason}
+		_ = json.NewEncoder(w).Encode(resp)
+		return false
+	}
+	// Deprecated service types still match the tenders filed under them.
+	for _, serviceType := range req.ServiceTypes {
+		if !s.validateServiceType(w, r, serviceType, true) {
+			return false
+		}
+	}
+	return true
+}
+
+// compareAmounts compares two amounts already checked with IsValidAmount.
+func compareAmounts(a, b string) int {
+	x, _ := new(big.Rat).SetString(a)
+	y, _ := new(big.Rat).SetString(b)
+	return x.Cmp(y)
+}
+
+func nullableString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func savedSearchAuditEntry(action string, search *model.SavedSearch) *model.AuditEntry {
+	return &model.AuditEntry{
+		Action:     action,
+		EntityType: model.EntitySavedSearch,
+		EntityID:   search.ID,
+	}
+}
+
+func savedSearchToResponse(search *model.SavedSearch) *SavedSearchResponse {
+	return &SavedSearchResponse{
+		ID:             search.ID,
+		Name:           search.Name,
+		ServiceTypes:   search.ServiceTypes,
+		Keywords:       search.Keywords,
+		BudgetMin:      search.BudgetMin,
+		BudgetMax:      search.BudgetMax,
+		BudgetCurrency: search.BudgetCurrency,
+		CreatedAt:      JSONTime(search.CreatedAt),
+	}
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestNewSavedSearchRequiresCurrencyWithBudget(t *testing.T) {
+	s := newTestServer(newFakeBidDb())
+	for _, req := range []SavedSearchRequest{
+		{Name: "Roadworks", BudgetMin: "1000"},
+		{Name: "Roadworks", BudgetMax: "5000"},
+	} {
+		rec := serve(t, s, http.MethodPost, "/api/searches/new?username=author", req)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusBadRequest, rec.Body)
+		}
+		var resp ErrResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Reason != "budgetCurrency is required with budgetMin or budgetMax" {
+			t.Errorf("reason = %q", resp.Reason)
+		}
+	}
+}
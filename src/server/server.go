@@ -3,33 +3,133 @@ package server
 import (
 	"github.com/gorilla/mux"
 	"net/http"
+	"time"
 	"zadanie-6105/config"
 	"zadanie-6105/database"
+	"zadanie-6105/health"
+	"zadanie-6105/metrics"
+	"zadanie-6105/storage"
+	"zadanie-6105/stream"
 )
 
 type Server struct {
 	serverAddress string
 	db            database.DbConnector
+	root          *mux.Router
 	r             *mux.Router
+	admins        []string
+	blobs         storage.BlobStorage
+	attachments   attachmentLimits
+	stream        *stream.Hub
+	streamLimits  streamLimits
+	health        *health.Monitor
+	metrics       *metrics.Metrics
+	serviceTypes  *serviceTypeCache
 }
 
-func NewServer(cfg *config.Config, db database.DbConnector) *Server {
+type attachmentLimits struct {
+	MaxSize      int64
+	AllowedTypes []string
+}
+
+type streamLimits struct {
+	HeartbeatInterval time.Duration
+	ReplayLimit       int
+	WriteTimeout      time.Duration
+}
+
+func NewServer(
+	cfg *config.Config, db database.DbConnector, blobs storage.BlobStorage, hub *stream.Hub, monitor *health.Monitor,
+	appMetrics *metrics.Metrics,
+) *Server {
+	root := mux.NewRouter()
 	s := &Server{
 		serverAddress: cfg.ServerAddress,
 		db:            db,
-		r:             mux.NewRouter().PathPrefix("/api").Subrouter(),
+		root:          root,
+		r:             root.PathPrefix("/api").Subrouter(),
+		admins:        cfg.AdminUsernames,
+		blobs:         blobs,
+		attachments: attachmentLimits{
+			MaxSize:      cfg.AttachmentMaxSize,
+			AllowedTypes: cfg.AttachmentAllowedTypes,
+		},
+		stream: hub,
+		streamLimits: streamLimits{
+			HeartbeatInterval: cfg.StreamHeartbeatInterval,
+			ReplayLimit:       cfg.StreamReplayLimit,
+			WriteTimeout:      cfg.ServerWriteTimeout,
+		},
+		health:       monitor,
+		metrics:      appMetrics,
+		serviceTypes: newServiceTypeCache(db, cfg.ServiceTypeCacheTTL),
 	}
+	s.root.Handle("/metrics", s.metrics.Handler()).Methods(http.MethodGet)
+	s.r.Use(requestIDMiddleware)
+	s.r.Use(s.metrics.Middleware)
 	s.r.HandleFunc("/ping", s.ping).Methods(http.MethodGet)
+	s.r.HandleFunc("/ready", s.ready).Methods(http.MethodGet)
 	s.r.HandleFunc("/tenders", s.tenders).Methods(http.MethodGet)
 	s.r.HandleFunc("/tenders/new", s.newTender).Methods(http.MethodPost)
 	s.r.HandleFunc("/tenders/my", s.myTenders).Methods(http.MethodGet)
+	s.r.HandleFunc("/stream/tenders", s.streamTenders).Methods(http.MethodGet)
+	s.r.HandleFunc("/tenders/scheduled", s.scheduledTenders).Methods(http.MethodGet)
 	s.r.HandleFunc("/tenders/{tenderId}/status", s.tenderStatus).Methods(http.MethodGet)
 	s.r.HandleFunc("/tenders/{tenderId}/status", s.updateTenderStatus).Methods(http.MethodPut)
 	s.r.HandleFunc("/tenders/{tenderId}/edit", s.editTender).Methods(http.MethodPatch)
 	s.r.HandleFunc("/tenders/{tenderId}/rollback/{version}", s.rollbackVersion).Methods(http.MethodPut)
+	s.r.HandleFunc("/tenders/{tenderId}/clone", s.cloneTender).Methods(http.MethodPost)
+	s.r.HandleFunc("/tenders/{tenderId}/schedule", s.cancelTenderSchedule).Methods(http.MethodDelete)
+	s.r.HandleFunc("/tenders/{tenderId}/bids/compare", s.compareBids).Methods(http.MethodGet)
+	s.r.HandleFunc("/tenders/{tenderId}/attachments", s.tenderAttachments).Methods(http.MethodGet)
+	s.r.HandleFunc("/tenders/{tenderId}/attachments", s.uploadTenderAttachment).Methods(http.MethodPost)
+	s.r.HandleFunc("/bids/new", s.newBid).Methods(http.MethodPost)
+	s.r.HandleFunc("/bids/{bidId}/status", s.updateBidStatus).Methods(http.MethodPut)
+	s.r.HandleFunc("/bids/{bidId}/submit_decision", s.submitBidDecision).Methods(http.MethodPut)
+	s.r.HandleFunc("/bids/{bidId}/attachments", s.bidAttachments).Methods(http.MethodGet)
+	s.r.HandleFunc("/bids/{bidId}/attachments", s.uploadBidAttachment).Methods(http.MethodPost)
+	s.r.HandleFunc("/attachments/{attachmentId}", s.downloadAttachment).Methods(http.MethodGet)
+	s.r.HandleFunc("/tenders/{tenderId}/questions", s.tenderQuestions).Methods(http.MethodGet)
+	s.r.HandleFunc("/tenders/{tenderId}/questions/new", s.newQuestion).Methods(http.MethodPost)
+	s.r.HandleFunc("/questions/my", s.myQuestions).Methods(http.MethodGet)
+	s.r.HandleFunc("/tenders/{tenderId}/invitations", s.tenderInvitations).Methods(http.MethodGet)
+	s.r.HandleFunc("/tenders/{tenderId}/invitations/new", s.newInvitation).Methods(http.MethodPost)
+	s.r.HandleFunc("/tenders/{tenderId}/invitations/{organizationId}", s.deleteInvitation).Methods(http.MethodDelete)
+	s.r.HandleFunc("/tenders/{tenderId}/lots", s.tenderLots).Methods(http.MethodGet)
+	s.r.HandleFunc("/tenders/{tenderId}/lots/new", s.newLot).Methods(http.MethodPost)
+	s.r.HandleFunc("/lots/{lotId}/edit", s.editLot).Methods(http.MethodPatch)
+	s.r.HandleFunc("/lots/{lotId}/status", s.updateLotStatus).Methods(http.MethodPut)
+	s.r.HandleFunc("/questions/{questionId}/answer", s.answerQuestion).Methods(http.MethodPut)
+	s.r.HandleFunc("/organizations/{organizationId}/templates", s.organizationTemplates).Methods(http.MethodGet)
+	s.r.HandleFunc("/organizations/{organizationId}/templates/new", s.newTemplate).Methods(http.MethodPost)
+	s.r.HandleFunc("/templates/{templateId}", s.getTemplate).Methods(http.MethodGet)
+	s.r.HandleFunc("/templates/{templateId}", s.deleteTemplate).Methods(http.MethodDelete)
+	s.r.HandleFunc("/templates/{templateId}/edit", s.editTemplate).Methods(http.MethodPatch)
+	s.r.HandleFunc("/templates/{templateId}/tenders/new", s.tenderFromTemplate).Methods(http.MethodPost)
+	s.r.HandleFunc("/searches/my", s.mySavedSearches).Methods(http.MethodGet)
+	s.r.HandleFunc("/searches/new", s.newSavedSearch).Methods(http.MethodPost)
+	s.r.HandleFunc("/searches/{searchId}", s.deleteSavedSearch).Methods(http.MethodDelete)
+	s.r.HandleFunc("/notifications/my", s.myNotifications).Methods(http.MethodGet)
+	s.r.HandleFunc("/notifications/my/read", s.readAllNotifications).Methods(http.MethodPut)
+	s.r.HandleFunc("/notifications/{notificationId}/read", s.readNotification).Methods(http.MethodPut)
+	s.r.HandleFunc("/notifications/settings", s.emailPreferences).Methods(http.MethodGet)
+	s.r.HandleFunc("/notifications/settings", s.editEmailPreferences).Methods(http.MethodPut)
+	s.r.HandleFunc("/organizations/{organizationId}/webhooks", s.organizationWebhooks).Methods(http.MethodGet)
+	s.r.HandleFunc("/organizations/{organizationId}/webhooks/new", s.newWebhook).Methods(http.MethodPost)
+	s.r.HandleFunc("/webhooks/{webhookId}", s.getWebhook).Methods(http.MethodGet)
+	s.r.HandleFunc("/webhooks/{webhookId}", s.deleteWebhook).Methods(http.MethodDelete)
+	s.r.HandleFunc("/webhooks/{webhookId}/edit", s.editWebhook).Methods(http.MethodPatch)
+	s.r.HandleFunc("/webhooks/{webhookId}/deliveries", s.webhookDeliveries).Methods(http.MethodGet)
+	s.r.HandleFunc("/webhooks/{webhookId}/deliveries/{deliveryId}/redeliver", s.redeliverWebhook).
+		Methods(http.MethodPost)
+	s.r.HandleFunc("/organizations/{organizationId}/audit", s.auditEntries).Methods(http.MethodGet)
+	s.r.HandleFunc("/audit/verify", s.verifyChains).Methods(http.MethodGet)
+	s.r.HandleFunc("/service_types", s.listServiceTypes).Methods(http.MethodGet)
+	s.r.HandleFunc("/service_types/new", s.newServiceType).Methods(http.MethodPost)
+	s.r.HandleFunc("/service_types/{name}/edit", s.editServiceType).Methods(http.MethodPatch)
 	return s
 }
 
 func (s *Server) Router() *mux.Router {
-	return s.r
+	return s.root
 }
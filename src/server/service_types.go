@@ -0,0 +1,352 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+	"zadanie-6105/database"
+	"zadanie-6105/model"
+)
+
+const MaxServiceTypeLength = 100
+
+// serviceTypeCache keeps the service type tree in memory for ttl so that
+// request validation does not query it every time.
+type serviceTypeCache struct {
+	mu       sync.RWMutex
+	db       database.DbConnector
+	ttl      time.Duration
+	loadedAt time.Time
+	types    map[string]model.ServiceType
+}
+
+func newServiceTypeCache(db database.DbConnector, ttl time.Duration) *serviceTypeCache {
+	return &serviceTypeCache{db: db, ttl: ttl}
+}
+
+func (c *serviceTypeCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadedAt = time.Time{}
+}
+
+func (c *serviceTypeCache) snapshot(ctx context.Context) (map[string]model.ServiceType, error) {
+	c.mu.RLock()
+	if time.Since(c.loadedAt) < c.ttl {
+		defer c.mu.RUnlock()
+		return c.types, nil
+	}
+	c.mu.RUnlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Since(c.loadedAt) < c.ttl {
+		return c.types, nil
+	}
+	list, err := c.db.GetServiceTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	types := make(map[string]model.ServiceType, len(list))
+	for _, st := range list {
+		types[st.Name] = st
+	}
+	c.types = types
+	c.loadedAt = time.Now()
+	return c.types, nil
+}
+
+func (c *serviceTypeCache) lookup(ctx context.Context, name string) (model.ServiceType, bool, error) {
+	types, err := c.snapshot(ctx)
+	if err != nil {
+		return model.ServiceType{}, false, err
+	}
+	st, ok := types[name]
+	return st, ok, nil
+}
+
+// descendants returns name followed by all of its descendants, or nil if
+// there is no such service type.
+func (c *serviceTypeCache) descendants(ctx context.Context, name string) ([]string, error) {
+	types, err := c.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := types[name]; !ok {
+		return nil, nil
+	}
+	result := []string{name}
+	for i := 0; i < len(result); i++ {
+		for _, st := range types {
+			if st.Parent != nil && *st.Parent == result[i] && !slices.Contains(result, st.Name) {
+				result = append(result, st.Name)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (c *serviceTypeCache) isAncestor(ctx context.Context, ancestor, name string) (bool, error) {
+	types, err := c.snapshot(ctx)
+	if err != nil {
+		return false, err
+	}
+	for seen := 0; seen <= len(types); seen++ {
+		if name == ancestor {
+			return true, nil
+		}
+		st, ok := types[name]
+		if !ok || st.Parent == nil {
+			return false, nil
+		}
+		name = *st.Parent
+	}
+	return false, nil
+}
+
+// validateServiceType answers 400 unless serviceType exists and, when
+// deprecated ones are not allowed, is not deprecated.
+func (s *Server) validateServiceType(
+	w http.ResponseWriter, r *http.Request, serviceType string, allowDeprecated bool,
+) bool {
+	st, ok, err := s.serviceTypes.lookup(r.Context(), serviceType)
+	if err != nil {
+		writeServiceTypesError(w, err)
+		return false
+	}
+	if !ok || st.Deprecated && !allowDeprecated {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "service type is not available"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return false
+	}
+	return true
+}
+
+// validateServiceTypeFilter expands every requested service type with its
+// descendants.
+func (v *Validator) validateServiceTypeFilter(cache *serviceTypeCache, names []string) (bool, []string) {
+	var expanded []string
+	for _, name := range names {
+		descendants, err := cache.descendants(v.r.Context(), name)
+		if err != nil {
+			writeServiceTypesError(v.w, err)
+			return false, nil
+		}
+		if descendants == nil {
+			v.writeBadRequest("service type is not available")
+			return false, nil
+		}
+		expanded = append(expanded, descendants...)
+	}
+	return true, expanded
+}
+
+func writeServiceTypesError(w http.ResponseWriter, err error) {
+	slog.Warn("error getting service types", "error", err)
+	w.WriteHeader(http.StatusInternalServerError)
+	resp := ErrResponse{Reason: "error getting service types"}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) listServiceTypes(w http.ResponseWriter, r *http.Request) {
+	list, err := s.db.GetServiceTypes(r.Context())
+	if err != nil {
+		slog.Warn("error getting service types", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting service types"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := make([]*ServiceTypeResponse, 0, len(list))
+	for i := range list {
+		resp = append(resp, serviceTypeToResponse(&list[i]))
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) newServiceType(w http.ResponseWriter, r *http.Request) {
+	username := r.URL.Query().Get("username")
+	if !s.validateAdmin(w, r, username) {
+		return
+	}
+	var req ServiceTypeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Warn("error decoding body", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "error decoding body"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if req.Name == nil || !isValidServiceTypeName(*req.Name) {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "service type name is not valid"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	st := &model.ServiceType{Name: *req.Name, Parent: req.Parent}
+	if st.Parent != nil {
+		_, ok, err := s.serviceTypes.lookup(r.Context(), *st.Parent)
+		if err != nil {
+			writeServiceTypesError(w, err)
+			return
+		}
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			resp := ErrResponse{Reason: "parent service type not found"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+	}
+	err := s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if _, err := db.SaveServiceType(r.Context(), st); err != nil {
+			return err
+		}
+		return s.audit(db, r, username, &model.AuditEntry{
+			Action:     model.AuditServiceTypeCreate,
+			EntityType: model.EntityServiceType,
+			EntityID:   st.Name,
+		}, nil, st)
+	})
+	if err != nil {
+		s.writeServiceTypeError(w, err)
+		return
+	}
+	s.serviceTypes.invalidate()
+	resp := serviceTypeToResponse(st)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) editServiceType(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	username := r.URL.Query().Get("username")
+	if !s.validateAdmin(w, r, username) {
+		return
+	}
+	current, ok, err := s.serviceTypes.lookup(r.Context(), name)
+	if err != nil {
+		writeServiceTypesError(w, err)
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		resp := ErrResponse{Reason: "service type not found"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	var req ServiceTypeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Warn("error decoding body", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "error decoding body"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	st := current
+	if req.Name != nil {
+		if !isValidServiceTypeName(*req.Name) {
+			w.WriteHeader(http.StatusBadRequest)
+			resp := ErrResponse{Reason: "service type name is not valid"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		st.Name = *req.Name
+	}
+	if req.Parent != nil {
+		if *req.Parent == "" {
+			st.Parent = nil
+		} else {
+			_, known, err := s.serviceTypes.lookup(r.Context(), *req.Parent)
+			if err != nil {
+				writeServiceTypesError(w, err)
+				return
+			}
+			cyclic, err := s.serviceTypes.isAncestor(r.Context(), name, *req.Parent)
+			if err != nil {
+				writeServiceTypesError(w, err)
+				return
+			}
+			if !known || cyclic {
+				w.WriteHeader(http.StatusBadRequest)
+				resp := ErrResponse{Reason: "parent service type is not valid"}
+				_ = json.NewEncoder(w).Encode(resp)
+				return
+			}
+			st.Parent = req.Parent
+		}
+	}
+	if req.Deprecated != nil {
+		st.Deprecated = *req.Deprecated
+	}
+	err = s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if _, err := db.UpdateServiceType(r.Context(), name, &st); err != nil {
+			return err
+		}
+		return s.audit(db, r, username, &model.AuditEntry{
+			Action:     model.AuditServiceTypeEdit,
+			EntityType: model.EntityServiceType,
+			EntityID:   name,
+		}, current, st)
+	})
+	if err != nil {
+		s.writeServiceTypeError(w, err)
+		return
+	}
+	s.serviceTypes.invalidate()
+	resp := serviceTypeToResponse(&st)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) writeServiceTypeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, database.ErrServiceTypeNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		resp := ErrResponse{Reason: "service type not found"}
+		_ = json.NewEncoder(w).Encode(resp)
+	case errors.Is(err, database.ErrServiceTypeExists):
+		w.WriteHeader(http.StatusConflict)
+		resp := ErrResponse{Reason: "service type already exists"}
+		_ = json.NewEncoder(w).Encode(resp)
+	default:
+		slog.Warn("error saving service type", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error saving service type"}
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func (s *Server) validateAdmin(w http.ResponseWriter, r *http.Request, username string) bool {
+	validator := NewValidator(w, r, s.db)
+	if !validator.ValidateUsername(username) {
+		return false
+	}
+	if !slices.Contains(s.admins, username) {
+		w.WriteHeader(http.StatusForbidden)
+		resp := ErrResponse{Reason: "user is not an administrator"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return false
+	}
+	return true
+}
+
+func isValidServiceTypeName(name string) bool {
+	return name != "" && len(name) <= MaxServiceTypeLength
+}
+
+func serviceTypeToResponse(st *model.ServiceType) *ServiceTypeResponse {
+	return &ServiceTypeResponse{
+		Name:       st.Name,
+		Parent:     st.Parent,
+		Deprecated: st.Deprecated,
+		CreatedAt:  JSONTime(st.CreatedAt),
+	}
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"zadanie-6105/model"
+	"zadanie-6105/outbox"
+	"zadanie-6105/stream"
+)
+
+const LastEventIDHeader = "Last-Event-ID"
+
+// streamTenders sends tender events as server-sent events. Clients resume
+// after a reconnect with the Last-Event-ID header (or the lastEventId query
+// parameter); when more events were missed than the replay limit allows, a
+// "reset" event tells the client to reload tenders through GET /api/tenders.
+func (s *Server) streamTenders(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	ok, filter := validator.validateStreamFilter(s.serviceTypes)
+	if !ok {
+		return
+	}
+	var employee *model.Employee
+	if username := r.URL.Query().Get("username"); username != "" {
+		if !validator.ValidateUsername(username) {
+			return
+		}
+		if employee, ok = s.getEmployee(w, r, username); !ok {
+			return
+		}
+	}
+	lastEventID := r.Header.Get(LastEventIDHeader)
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+	var after int64
+	if lastEventID != "" {
+		var err error
+		if after, err = strconv.ParseInt(lastEventID, 10, 64); err != nil || after < 0 {
+			validator.writeBadRequest("last event id is not valid")
+			return
+		}
+	}
+
+	sub, boundary := s.stream.Subscribe(*filter)
+	defer s.stream.Unsubscribe(sub)
+	rc := http.NewResponseController(w)
+	// The server write timeout covers the whole response, so the stream moves
+	// the deadline forward before every write instead. The hub closes the
+	// subscription on shutdown, which ends the stream.
+	extendDeadline := func() error {
+		deadline := time.Time{}
+		if s.streamLimits.WriteTimeout > 0 {
+			deadline = time.Now().Add(s.streamLimits.WriteTimeout)
+		}
+		return rc.SetWriteDeadline(deadline)
+	}
+	if err := extendDeadline(); err != nil {
+		slog.Warn("error setting event stream write deadline", "error", err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if _, err := fmt.Fprint(w, "retry: 3000\n\n"); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		slog.Warn("error flushing event stream", "error", err)
+		return
+	}
+
+	if after > 0 && after < boundary && !s.replayTenderEvents(w, r, filter, employee, after, boundary) {
+		return
+	}
+	// The client may be ahead of this instance's hub after reconnecting to
+	// another instance, so skip events it has already seen.
+	seen := max(boundary, after)
+	heartbeat := time.NewTicker(s.streamLimits.HeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if extendDeadline() != nil {
+				return
+			}
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if event.ID <= seen || !s.isStreamEventVisible(r.Context(), event, employee) {
+				continue
+			}
+			if extendDeadline() != nil || writeStreamEvent(w, event) != nil {
+				return
+			}
+		}
+		if rc.Flush() != nil {
+			return
+		}
+	}
+}
+
+// replayTenderEvents writes the events stored after the client's last event
+// up to boundary. It returns false when the stream should be closed.
+func (s *Server) replayTenderEvents(
+	w http.ResponseWriter, r *http.Request, filter *stream.Filter, employee *model.Employee, after, boundary int64,
+) bool {
+	limit := s.streamLimits.ReplayLimit
+	events, err := s.db.GetOutboxEvents(r.Context(), after, boundary, limit+1)
+	if err != nil {
+		slog.Warn("error getting outbox events", "error", err)
+		return false
+	}
+	if len(events) > limit {
+		_, err = fmt.Fprintf(w, "id: %d\nevent: reset\ndata: {}\n\n", boundary)
+		return err == nil
+	}
+	for i := range events {
+		event, ok := stream.NewEvent(&events[i])
+		if !ok || !filter.Match(event) || !s.isStreamEventVisible(r.Context(), event, employee) {
+			continue
+		}
+		if writeStreamEvent(w, event) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// isStreamEventVisible applies the tender list rules to the tender version in
+// the event: published tenders are shown to everyone unless they are
+// invite-only, drafts and closed tenders only to their creator.
+func (s *Server) isStreamEventVisible(ctx context.Context, event *stream.Event, employee *model.Employee) bool {
+	tender := &model.Tender{
+		ID:             event.Tender.ID,
+		Status:         model.TenderStatus(event.Tender.Status),
+		OrganizationID: event.Tender.OrganizationID,
+		Visibility:     model.TenderVisibility(event.Tender.Visibility),
+	}
+	if tender.Status == model.TenderPublished && tender.Visibility == model.TenderPublic {
+		return true
+	}
+	if employee == nil {
+		return false
+	}
+	// The creator is not part of the event payload.
+	current, err := s.db.GetTenderByID(ctx, tender.ID)
+	if err != nil {
+		slog.Warn("error checking stream event visibility", "error", err, "id", event.ID)
+		return false
+	}
+	tender.CreatorID = current.CreatorID
+	invited := false
+	if tender.Status == model.TenderPublished && tender.CreatorID != employee.ID {
+		if invited, err = s.db.IsTenderInvitee(ctx, tender.ID, tender.OrganizationID, employee.ID); err != nil {
+			slog.Warn("error checking stream event visibility", "error", err, "id", event.ID)
+			return false
+		}
+	}
+	return IsTenderAvailable(tender, employee, invited)
+}
+
+func writeStreamEvent(w http.ResponseWriter, event *stream.Event) error {
+	data, err := json.Marshal(outbox.NewEnvelope(event.OutboxEvent))
+	if err != nil {
+		slog.Warn("error encoding stream event", "error", err, "id", event.ID)
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
+
+func (v *Validator) validateStreamFilter(serviceTypes *serviceTypeCache) (bool, *stream.Filter) {
+	query := v.r.URL.Query()
+	filter := &stream.Filter{}
+	var ok bool
+	if ok, filter.ServiceTypes = v.validateServiceTypeFilter(serviceTypes, query["service_type"]); !ok {
+		return false, nil
+	}
+	filter.OrganizationIDs = query["organization_id"]
+	for _, id := range filter.OrganizationIDs {
+		if !IsValidUuid(id) {
+			v.writeBadRequest("organization id is not valid")
+			return false, nil
+		}
+	}
+	return true, filter
+}
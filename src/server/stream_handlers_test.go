@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"zadanie-6105/database"
+	"zadanie-6105/model"
+	"zadanie-6105/stream"
+)
+
+var (
+	testOtherResponsible = model.Employee{ID: "00000000-0000-0000-0000-0000000000c3", Username: "other"}
+	testInvitee          = model.Employee{ID: "00000000-0000-0000-0000-0000000000c4", Username: "invitee"}
+)
+
+// fakeStreamDb mirrors the queries of the postgres connector for one tender
+// created by testResponsible. testOtherResponsible is a responsible of the same
+// organization and testInvitee of an invited one.
+type fakeStreamDb struct {
+	database.DbConnector
+}
+
+func (db *fakeStreamDb) GetTenderByID(_ context.Context, id string) (*model.Tender, error) {
+	if id != testTenderID {
+		return nil, database.ErrTenderNotFound
+	}
+	return &model.Tender{ID: testTenderID, OrganizationID: testOrganizationID, CreatorID: testResponsible.ID}, nil
+}
+
+func (db *fakeStreamDb) IsTenderInvitee(_ context.Context, tenderID, organizationID, employeeID string) (bool, error) {
+	return tenderID == testTenderID && organizationID == testOrganizationID &&
+		(employeeID == testResponsible.ID || employeeID == testOtherResponsible.ID || employeeID == testInvitee.ID), nil
+}
+
+func TestStreamEventVisibilityMatchesTenderList(t *testing.T) {
+	db := &fakeStreamDb{}
+	s := newTestServer(db)
+	tests := []struct {
+		name       string
+		status     model.TenderStatus
+		visibility model.TenderVisibility
+		viewer     *model.Employee
+		want       bool
+	}{
+		{"anonymous sees public published", model.TenderPublished, model.TenderPublic, nil, true},
+		{"anonymous does not see invite-only", model.TenderPublished, model.TenderInviteOnly, nil, false},
+		{"anonymous does not see draft", model.TenderCreated, model.TenderPublic, nil, false},
+		{"anonymous does not see closed", model.TenderClosed, model.TenderPublic, nil, false},
+		{"creator sees draft", model.TenderCreated, model.TenderPublic, &testResponsible, true},
+		{"creator sees closed", model.TenderClosed, model.TenderInviteOnly, &testResponsible, true},
+		{"other responsible does not see draft", model.TenderCreated, model.TenderPublic, &testOtherResponsible, false},
+		{"other responsible does not see closed", model.TenderClosed, model.TenderPublic, &testOtherResponsible, false},
+		{"other responsible sees invite-only", model.TenderPublished, model.TenderInviteOnly, &testOtherResponsible, true},
+		{"invitee sees invite-only", model.TenderPublished, model.TenderInviteOnly, &testInvitee, true},
+		{"invitee does not see draft", model.TenderCreated, model.TenderInviteOnly, &testInvitee, false},
+		{"invitee does not see closed", model.TenderClosed, model.TenderInviteOnly, &testInvitee, false},
+		{"stranger does not see invite-only", model.TenderPublished, model.TenderInviteOnly, &testAuthor, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := model.TenderEventPayload{
+				ID:             testTenderID,
+				Status:         string(tt.status),
+				OrganizationID: testOrganizationID,
+				Visibility:     string(tt.visibility),
+			}
+			data, err := json.Marshal(payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			event, ok := stream.NewEvent(&model.OutboxEvent{
+				Type:          model.EventTenderEdited,
+				AggregateType: model.EntityTender,
+				AggregateID:   testTenderID,
+				Payload:       data,
+			})
+			if !ok {
+				t.Fatal("event was not decoded")
+			}
+			if got := s.isStreamEventVisible(context.Background(), event, tt.viewer); got != tt.want {
+				t.Errorf("isStreamEventVisible() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
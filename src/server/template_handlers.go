@@ -0,0 +1,398 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/samber/lo"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"zadanie-6105/database"
+	"zadanie-6105/model"
+)
+
+const (
+	MaxTemplateNameLength        = 100
+	MaxTemplateTenderNameLength  = 500
+	MaxTemplateDescriptionLength = 2000
+)
+
+// placeholderRegexp matches template fields such as {{region}} or {{ quantity }}.
+var placeholderRegexp = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+func (s *Server) organizationTemplates(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	organizationId := mux.Vars(r)["organizationId"]
+	username := r.URL.Query().Get("username")
+	ok, limit, offset := validator.ValidatePagination(r.URL.Query().Get("limit"), r.URL.Query().Get("offset"))
+	if !ok {
+		return
+	}
+	if !validator.ValidateUuid(organizationId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	if !s.checkOrganizationResponsible(w, r, username, organizationId) {
+		return
+	}
+	templates, err := s.db.GetOrganizationTemplates(r.Context(), limit, offset, organizationId)
+	if err != nil {
+		slog.Warn("error getting organization templates", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting templates"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := lo.Map(templates, func(tpl model.TenderTemplate, _ int) *TemplateResponse {
+		return templateToResponse(&tpl)
+	})
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) newTemplate(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	organizationId := mux.Vars(r)["organizationId"]
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUuid(organizationId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	var req TemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Warn("error decoding body", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "error decoding body"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if !s.validateTemplateRequest(w, r, &req) {
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	if !s.checkOrganizationResponsible(w, r, username, organizationId) {
+		return
+	}
+	tpl := &model.TenderTemplate{
+		OrganizationID:    organizationId,
+		Name:              req.Name,
+		TenderName:        req.TenderName,
+		TenderDescription: req.TenderDescription,
+		ServiceType:       req.ServiceType,
+		Visibility:        model.TenderVisibility(req.Visibility),
+		Budget:            requestToMoney(req.Budget),
+		CreatorID:         employee.ID,
+	}
+	err := s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if _, err := db.SaveTemplate(r.Context(), tpl); err != nil {
+			return err
+		}
+		return s.audit(db, r, username, templateAuditEntry(model.AuditTemplateCreate, tpl), nil, tpl)
+	})
+	if err != nil {
+		s.writeTemplateError(w, err, "error saving template")
+		return
+	}
+	resp := templateToResponse(tpl)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) getTemplate(w http.ResponseWriter, r *http.Request) {
+	tpl, ok := s.templateForResponsible(w, r)
+	if !ok {
+		return
+	}
+	resp := templateToResponse(tpl)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) editTemplate(w http.ResponseWriter, r *http.Request) {
+	tpl, ok := s.templateForResponsible(w, r)
+	if !ok {
+		return
+	}
+	var req TemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Warn("error decoding body", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "error decoding body"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if req.Name == "" {
+		req.Name = tpl.Name
+	}
+	if req.TenderName == "" {
+		req.TenderName = tpl.TenderName
+	}
+	if req.TenderDescription == "" {
+		req.TenderDescription = tpl.TenderDescription
+	}
+	if req.ServiceType == "" {
+		req.ServiceType = tpl.ServiceType
+	}
+	if req.Visibility == "" {
+		req.Visibility = string(tpl.Visibility)
+	}
+	if !s.validateTemplateRequest(w, r, &req) {
+		return
+	}
+	before := *tpl
+	tpl.Name = req.Name
+	tpl.TenderName = req.TenderName
+	tpl.TenderDescription = req.TenderDescription
+	tpl.ServiceType = req.ServiceType
+	tpl.Visibility = model.TenderVisibility(req.Visibility)
+	if req.Budget != nil {
+		tpl.Budget = requestToMoney(req.Budget)
+	}
+	err := s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if _, err := db.UpdateTemplate(r.Context(), tpl); err != nil {
+			return err
+		}
+		return s.audit(db, r, r.URL.Query().Get("username"), templateAuditEntry(model.AuditTemplateEdit, tpl), &before, tpl)
+	})
+	if err != nil {
+		s.writeTemplateError(w, err, "error updating template")
+		return
+	}
+	resp := templateToResponse(tpl)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) deleteTemplate(w http.ResponseWriter, r *http.Request) {
+	tpl, ok := s.templateForResponsible(w, r)
+	if !ok {
+		return
+	}
+	err := s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if err := db.DeleteTemplate(r.Context(), tpl.ID); err != nil {
+			return err
+		}
+		return s.audit(db, r, r.URL.Query().Get("username"), templateAuditEntry(model.AuditTemplateDelete, tpl), tpl, nil)
+	})
+	if err != nil {
+		s.writeTemplateError(w, err, "error deleting template")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// tenderFromTemplate creates a draft tender by substituting the request values
+// into the template placeholders. Every placeholder must have a value.
+func (s *Server) tenderFromTemplate(w http.ResponseWriter, r *http.Request) {
+	tpl, ok := s.templateForResponsible(w, r)
+	if !ok {
+		return
+	}
+	var req TenderFromTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Warn("error decoding body", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "error decoding body"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if missing := missingPlaceholders(req.Values, tpl.TenderName, tpl.TenderDescription); len(missing) > 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := MissingPlaceholdersResponse{
+			Reason:  "missing placeholder values: " + strings.Join(missing, ", "),
+			Missing: missing,
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	name := renderTemplate(tpl.TenderName, req.Values)
+	description := renderTemplate(tpl.TenderDescription, req.Values)
+	if name == "" || len(name) > 100 {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "tender name is empty or too long. Max length is 100"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if len(description) > 500 {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "tender description is too long. Max length is 500"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if !s.validateServiceType(w, r, tpl.ServiceType, false) {
+		return
+	}
+	if !IsValidDeadline(req.Deadline, time.Now()) {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "deadline must be in the future"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if !IsValidPublishAt(req.PublishAt, req.Deadline, time.Now()) {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "publishAt must be in the future and before deadline"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	employee, ok := s.getEmployee(w, r, r.URL.Query().Get("username"))
+	if !ok {
+		return
+	}
+	tender := &model.Tender{
+		Name:           name,
+		Description:    description,
+		ServiceType:    tpl.ServiceType,
+		Status:         model.TenderCreated,
+		OrganizationID: tpl.OrganizationID,
+		CreatorID:      employee.ID,
+		Deadline:       utcTime(req.Deadline),
+		PublishAt:      utcTime(req.PublishAt),
+		Budget:         tpl.Budget,
+		Visibility:     tpl.Visibility,
+	}
+	err := s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if _, err := db.SaveTender(r.Context(), tender, model.EventTenderCreated); err != nil {
+			return err
+		}
+		return s.audit(db, r, employee.Username, tenderAuditEntry(model.AuditTenderCreateFromTemplate, tender), tpl, tender)
+	})
+	if err != nil {
+		slog.Warn("error saving tender", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error saving tender"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := tenderToResponse(tender)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+// templateForResponsible loads the template from the route and checks that the
+// user is responsible for the template's organization.
+func (s *Server) templateForResponsible(w http.ResponseWriter, r *http.Request) (*model.TenderTemplate, bool) {
+	validator := NewValidator(w, r, s.db)
+	templateId := mux.Vars(r)["templateId"]
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUuid(templateId) {
+		return nil, false
+	}
+	if !validator.ValidateUsername(username) {
+		return nil, false
+	}
+	tpl, err := s.db.GetTemplateByID(r.Context(), templateId)
+	if err != nil {
+		s.writeTemplateError(w, err, "error getting template by id")
+		return nil, false
+	}
+	if !s.checkOrganizationResponsible(w, r, username, tpl.OrganizationID) {
+		return nil, false
+	}
+	return tpl, true
+}
+
+func (s *Server) writeTemplateError(w http.ResponseWriter, err error, reason string) {
+	switch {
+	case errors.Is(err, database.ErrTemplateNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		resp := ErrResponse{Reason: "template not found"}
+		_ = json.NewEncoder(w).Encode(resp)
+	case errors.Is(err, database.ErrOrganizationNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		resp := ErrResponse{Reason: "organization not found"}
+		_ = json.NewEncoder(w).Encode(resp)
+	case errors.Is(err, database.ErrTemplateExists):
+		w.WriteHeader(http.StatusConflict)
+		resp := ErrResponse{Reason: "template with same name already exists"}
+		_ = json.NewEncoder(w).Encode(resp)
+	default:
+		slog.Warn(reason, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: reason}
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func (s *Server) validateTemplateRequest(w http.ResponseWriter, r *http.Request, req *TemplateRequest) bool {
+	reason := ""
+	switch {
+	case req.Name == "" || len(req.Name) > MaxTemplateNameLength:
+		reason = "name is empty or too long. Max length is 100"
+	case req.TenderName == "" || len(req.TenderName) > MaxTemplateTenderNameLength:
+		reason = "tender name is empty or too long. Max length is 500"
+	case len(req.TenderDescription) > MaxTemplateDescriptionLength:
+		reason = "tender description is too long. Max length is 2000"
+	case req.Visibility != "" && !IsValidTenderVisibility(req.Visibility):
+		reason = "visibility is not valid"
+	case !IsValidMoney(req.Budget):
+		reason = "budget is not valid"
+	}
+	if reason != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: reason}
+		_ = json.NewEncoder(w).Encode(resp)
+		return false
+	}
+	return s.validateServiceType(w, r, req.ServiceType, false)
+}
+
+// templatePlaceholders returns the sorted, distinct placeholder names used in texts.
+func templatePlaceholders(texts ...string) []string {
+	names := []string{}
+	for _, text := range texts {
+		for _, match := range placeholderRegexp.FindAllStringSubmatch(text, -1) {
+			names = append(names, match[1])
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+func missingPlaceholders(values map[string]string, texts ...string) []string {
+	return lo.Filter(templatePlaceholders(texts...), func(name string, _ int) bool {
+		_, ok := values[name]
+		return !ok
+	})
+}
+
+func renderTemplate(text string, values map[string]string) string {
+	return placeholderRegexp.ReplaceAllStringFunc(text, func(placeholder string) string {
+		return values[placeholderRegexp.FindStringSubmatch(placeholder)[1]]
+	})
+}
+
+func templateAuditEntry(action string, tpl *model.TenderTemplate) *model.AuditEntry {
+	return &model.AuditEntry{
+		Action:         action,
+		EntityType:     model.EntityTemplate,
+		EntityID:       tpl.ID,
+		OrganizationID: &tpl.OrganizationID,
+	}
+}
+
+func templateToResponse(tpl *model.TenderTemplate) *TemplateResponse {
+	return &TemplateResponse{
+		ID:                tpl.ID,
+		OrganizationID:    tpl.OrganizationID,
+		Name:              tpl.Name,
+		TenderName:        tpl.TenderName,
+		TenderDescription: tpl.TenderDescription,
+		ServiceType:       tpl.ServiceType,
+		Visibility:        string(tpl.Visibility),
+		Budget:            moneyToResponse(tpl.Budget),
+		Placeholders:      templatePlaceholders(tpl.TenderName, tpl.TenderDescription),
+		CreatedAt:         JSONTime(tpl.CreatedAt),
+		UpdatedAt:         JSONTime(tpl.UpdatedAt),
+	}
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"zadanie-6105/database"
+	"zadanie-6105/model"
+)
+
+const (
+	sortOrderAsc  = "asc"
+	sortOrderDesc = "desc"
+)
+
+func (v *Validator) ValidateTenderFilter(serviceTypes *serviceTypeCache) (bool, *database.TenderFilter) {
+	query := v.r.URL.Query()
+	filter := &database.TenderFilter{}
+
+	var ok bool
+	if ok, filter.Limit, filter.Offset = v.ValidatePagination(query.Get("limit"), query.Get("offset")); !ok {
+		return false, nil
+	}
+	if ok, filter.ServiceTypes = v.validateServiceTypeFilter(serviceTypes, query["service_type"]); !ok {
+		return false, nil
+	}
+	filter.OrganizationIDs = query["organization_id"]
+	for _, id := range filter.OrganizationIDs {
+		if !IsValidUuid(id) {
+			v.writeBadRequest("organization id is not valid")
+			return false, nil
+		}
+	}
+	for _, status := range query["status"] {
+		if !IsValidTenderStatus(status) {
+			v.writeBadRequest("status is not valid")
+			return false, nil
+		}
+		filter.Statuses = append(filter.Statuses, model.TenderStatus(status))
+	}
+	if filter.Search = query.Get("q"); len(filter.Search) > MaxSearchQueryLength {
+		v.writeBadRequest("search query is too long. Max length is " + strconv.Itoa(MaxSearchQueryLength))
+		return false, nil
+	}
+	for _, bound := range []struct {
+		param string
+		dst   **time.Time
+	}{
+		{param: "created_from", dst: &filter.CreatedFrom},
+		{param: "created_to", dst: &filter.CreatedTo},
+		{param: "updated_from", dst: &filter.UpdatedFrom},
+		{param: "updated_to", dst: &filter.UpdatedTo},
+	} {
+		if !v.parseTime(query.Get(bound.param), bound.param, bound.dst) {
+			return false, nil
+		}
+	}
+	for _, bound := range []struct {
+		param string
+		dst   *string
+	}{
+		{param: "budget_min", dst: &filter.BudgetMin},
+		{param: "budget_max", dst: &filter.BudgetMax},
+	} {
+		if value := query.Get(bound.param); value != "" {
+			if !IsValidAmount(value) {
+				v.writeBadRequest(bound.param + " is not a valid amount")
+				return false, nil
+			}
+			*bound.dst = value
+		}
+	}
+	if filter.BudgetCurrency = query.Get("budget_currency"); filter.BudgetCurrency != "" && !IsValidCurrency(filter.BudgetCurrency) {
+		v.writeBadRequest("budget_currency is not a valid ISO 4217 code")
+		return false, nil
+	}
+	// Amounts in different currencies are not comparable.
+	if (filter.BudgetMin != "" || filter.BudgetMax != "") && filter.BudgetCurrency == "" {
+		v.writeBadRequest("budget_currency is required with budget_min or budget_max")
+		return false, nil
+	}
+	if sortBy := query.Get("sort_by"); sortBy != "" {
+		if !database.IsValidTenderSortField(sortBy) {
+			v.writeBadRequest("sort_by is not valid")
+			return false, nil
+		}
+		filter.SortBy = database.TenderSortField(sortBy)
+	}
+	switch query.Get("sort_order") {
+	case "", sortOrderAsc:
+	case sortOrderDesc:
+		filter.Descending = true
+	default:
+		v.writeBadRequest("sort_order is not valid")
+		return false, nil
+	}
+	if ok, filter.After = v.ValidateCursor(query.Get("after"), filter.Offset); !ok {
+		return false, nil
+	}
+	if filter.After != nil {
+		if filter.IsRankedBySearch() {
+			v.writeBadRequest("after can not be combined with q without sort_by")
+			return false, nil
+		}
+		if !cursorMatchesFilter(filter.After, filter) {
+			v.writeBadRequest("after does not match sort_by and sort_order")
+			return false, nil
+		}
+	}
+	if creator := query.Get("creator_username"); creator != "" {
+		employee, err := v.organizationDb.GetEmployeeByUsername(v.r.Context(), creator)
+		if err != nil {
+			if errors.Is(err, database.ErrEmployeeNotFound) {
+				v.writeBadRequest("creator not found")
+				return false, nil
+			}
+			slog.Warn("error getting employee by username", "error", err)
+			v.w.WriteHeader(http.StatusInternalServerError)
+			resp := ErrResponse{Reason: "error getting employee by username"}
+			_ = json.NewEncoder(v.w).Encode(resp)
+			return false, nil
+		}
+		filter.CreatorID = employee.ID
+	}
+	return true, filter
+}
+
+func (v *Validator) parseTime(value, param string, dst **time.Time) bool {
+	if value == "" {
+		return true
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		v.writeBadRequest(param + " is not a valid RFC3339 time")
+		return false
+	}
+	t = t.UTC()
+	*dst = &t
+	return true
+}
+
+func (v *Validator) writeBadRequest(reason string) {
+	v.w.WriteHeader(http.StatusBadRequest)
+	resp := ErrResponse{Reason: reason}
+	_ = json.NewEncoder(v.w).Encode(resp)
+}
@@ -5,54 +5,36 @@ import (
 	"errors"
 	"github.com/gorilla/mux"
 	"github.com/samber/lo"
+	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 	"zadanie-6105/database"
 	"zadanie-6105/model"
 )
 
 func (s *Server) tenders(w http.ResponseWriter, r *http.Request) {
-	var (
-		limit       = 5
-		offset      = 0
-		serviceType []string
-		err         error
-	)
-
-	limitStr := r.URL.Query().Get("limit")
-	if limitStr != "" {
-		limit, err = strconv.Atoi(limitStr)
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			resp := ErrResponse{Reason: "limit is not integer"}
-			_ = json.NewEncoder(w).Encode(resp)
-			return
-		}
+	validator := NewValidator(w, r, s.db)
+	ok, filter := validator.ValidateTenderFilter(s.serviceTypes)
+	if !ok {
+		return
 	}
-	offsetStr := r.URL.Query().Get("offset")
-	if offsetStr != "" {
-		offset, err = strconv.Atoi(offsetStr)
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			resp := ErrResponse{Reason: "offset is not integer"}
-			_ = json.NewEncoder(w).Encode(resp)
+	if username := r.URL.Query().Get("username"); username != "" {
+		if !validator.ValidateUsername(username) {
 			return
 		}
-	}
-	serviceType = r.URL.Query()["service_type"]
-	for _, st := range serviceType {
-		if !IsValidServiceType(st) {
-			w.WriteHeader(http.StatusBadRequest)
-			resp := ErrResponse{Reason: "service type is not available"}
+		employee, err := s.db.GetEmployeeByUsername(r.Context(), username)
+		if err != nil {
+			slog.Warn("error getting employee by username", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			resp := ErrResponse{Reason: "error getting employee by username"}
 			_ = json.NewEncoder(w).Encode(resp)
 			return
 		}
+		filter.ViewerID = employee.ID
 	}
-	if len(serviceType) == 0 {
-		serviceType = availableServiceTypes
-	}
-	tenders, err := s.db.GetTenders(r.Context(), limit, offset, serviceType)
+	tenders, err := s.db.GetTenders(r.Context(), filter)
 	if err != nil {
 		slog.Warn("error getting tenders", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -60,6 +42,9 @@ func (s *Server) tenders(w http.ResponseWriter, r *http.Request) {
 		_ = json.NewEncoder(w).Encode(resp)
 		return
 	}
+	if next := nextTenderCursor(tenders, filter); next != "" {
+		w.Header().Set(NextCursorHeader, next)
+	}
 	resp := tendersToResponse(tenders)
 	_ = json.NewEncoder(w).Encode(resp)
 	w.WriteHeader(http.StatusOK)
@@ -89,9 +74,30 @@ func (s *Server) newTender(w http.ResponseWriter, r *http.Request) {
 		_ = json.NewEncoder(w).Encode(resp)
 		return
 	}
-	if !IsValidServiceType(req.ServiceType) {
+	if !s.validateServiceType(w, r, req.ServiceType, false) {
+		return
+	}
+	if !IsValidDeadline(req.Deadline, time.Now()) {
 		w.WriteHeader(http.StatusBadRequest)
-		resp := ErrResponse{Reason: "service type is not available"}
+		resp := ErrResponse{Reason: "deadline must be in the future"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if !IsValidPublishAt(req.PublishAt, req.Deadline, time.Now()) {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "publishAt must be in the future and before deadline"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if !IsValidMoney(req.Budget) {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "budget is not valid"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if req.Visibility != "" && !IsValidTenderVisibility(req.Visibility) {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "visibility is not valid"}
 		_ = json.NewEncoder(w).Encode(resp)
 		return
 	}
@@ -139,7 +145,13 @@ func (s *Server) newTender(w http.ResponseWriter, r *http.Request) {
 	}
 	tender := requestToTender(&req, employee.ID)
 	tender.Status = model.TenderCreated
-	if _, err := s.db.SaveTender(r.Context(), tender); err != nil {
+	err = s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if _, err := db.SaveTender(r.Context(), tender, model.EventTenderCreated); err != nil {
+			return err
+		}
+		return s.audit(db, r, req.CreatorUsername, tenderAuditEntry(model.AuditTenderCreate, tender), nil, tender)
+	})
+	if err != nil {
 		if errors.Is(err, database.ErrTenderAlreadyExists) {
 			w.WriteHeader(http.StatusBadRequest)
 			resp := ErrResponse{Reason: "tender already exists"}
@@ -157,18 +169,13 @@ func (s *Server) newTender(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// myTenders lists tenders created by the employee. It accepts the same
+// filters, sorting and cursors as the tender list.
 func (s *Server) myTenders(w http.ResponseWriter, r *http.Request) {
-	var (
-		limit  = 5
-		offset = 0
-		err    error
-	)
 	validator := NewValidator(w, r, s.db)
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
 	username := r.URL.Query().Get("username")
-	var ok bool
-	if ok, limit, offset = validator.ValidatePagination(limitStr, offsetStr); !ok {
+	ok, filter := validator.ValidateTenderFilter(s.serviceTypes)
+	if !ok {
 		return
 	}
 	if !validator.ValidateUsername(username) {
@@ -188,7 +195,18 @@ func (s *Server) myTenders(w http.ResponseWriter, r *http.Request) {
 		_ = json.NewEncoder(w).Encode(resp)
 		return
 	}
-	tenders, err := s.db.GetTendersByCreatorID(r.Context(), limit, offset, employee.ID)
+	filter.ViewerID, filter.CreatorID = employee.ID, employee.ID
+	tenders, err := s.db.GetTenders(r.Context(), filter)
+	if err != nil {
+		slog.Warn("error getting tenders by creator id", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting tenders"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if next := nextTenderCursor(tenders, filter); next != "" {
+		w.Header().Set(NextCursorHeader, next)
+	}
 	resp := tendersToResponse(tenders)
 	_ = json.NewEncoder(w).Encode(resp)
 	w.WriteHeader(http.StatusOK)
@@ -232,10 +250,7 @@ func (s *Server) tenderStatus(w http.ResponseWriter, r *http.Request) {
 		_ = json.NewEncoder(w).Encode(resp)
 		return
 	}
-	if !IsTenderAvailable(tender, employee) {
-		w.WriteHeader(http.StatusForbidden)
-		resp := ErrResponse{Reason: "tender is not available"}
-		_ = json.NewEncoder(w).Encode(resp)
+	if !s.checkTenderAvailable(w, r, tender, employee) {
 		return
 	}
 	_ = json.NewEncoder(w).Encode(tender.Status)
@@ -299,14 +314,24 @@ func (s *Server) updateTenderStatus(w http.ResponseWriter, r *http.Request) {
 		_ = json.NewEncoder(w).Encode(resp)
 		return
 	}
-	if !IsTenderAvailable(tender, employee) {
-		w.WriteHeader(http.StatusForbidden)
-		resp := ErrResponse{Reason: "tender is not available"}
+	if !s.checkTenderAvailable(w, r, tender, employee) {
+		return
+	}
+	if model.TenderStatus(status) == model.TenderPublished && !IsValidDeadline(tender.Deadline, time.Now()) {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "tender deadline has passed"}
 		_ = json.NewEncoder(w).Encode(resp)
 		return
 	}
+	before := *tender
 	tender.Status = model.TenderStatus(status)
-	if _, err := s.db.UpdateTender(r.Context(), tender); err != nil {
+	err = s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if _, err := db.UpdateTender(r.Context(), tender, model.TenderStatusEvent(tender.Status)); err != nil {
+			return err
+		}
+		return s.audit(db, r, username, tenderAuditEntry(model.AuditTenderStatus, tender), &before, tender)
+	})
+	if err != nil {
 		if errors.Is(database.ErrTenderNotFound, err) {
 			w.WriteHeader(http.StatusNotFound)
 			resp := ErrResponse{Reason: "tender not found"}
@@ -386,12 +411,28 @@ func (s *Server) editTender(w http.ResponseWriter, r *http.Request) {
 		_ = json.NewEncoder(w).Encode(resp)
 		return
 	}
-	if req.ServiceType != "" && !IsValidServiceType(req.ServiceType) {
+	if req.ServiceType != "" && !s.validateServiceType(w, r, req.ServiceType, false) {
+		return
+	}
+	if !IsValidDeadline(req.Deadline, time.Now()) {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "deadline must be in the future"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if !IsValidMoney(req.Budget) {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "budget is not valid"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if req.Visibility != "" && !IsValidTenderVisibility(req.Visibility) {
 		w.WriteHeader(http.StatusBadRequest)
-		resp := ErrResponse{Reason: "service type is not available"}
+		resp := ErrResponse{Reason: "visibility is not valid"}
 		_ = json.NewEncoder(w).Encode(resp)
 		return
 	}
+	before := *tender
 	if req.Name != "" {
 		if len(req.Name) > 100 {
 			w.WriteHeader(http.StatusBadRequest)
@@ -407,8 +448,39 @@ func (s *Server) editTender(w http.ResponseWriter, r *http.Request) {
 	if req.ServiceType != "" {
 		tender.ServiceType = req.ServiceType
 	}
+	if req.Deadline != nil {
+		tender.Deadline = utcTime(req.Deadline)
+	}
+	if req.Budget != nil {
+		tender.Budget = requestToMoney(req.Budget)
+	}
+	if req.Visibility != "" {
+		tender.Visibility = model.TenderVisibility(req.Visibility)
+	}
+	if req.PublishAt != nil {
+		if tender.Status != model.TenderCreated {
+			w.WriteHeader(http.StatusBadRequest)
+			resp := ErrResponse{Reason: "only created tenders can be scheduled for publication"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		tender.PublishAt = utcTime(req.PublishAt)
+	}
+	if tender.PublishAt != nil && tender.Status == model.TenderCreated &&
+		!IsValidPublishAt(tender.PublishAt, tender.Deadline, time.Now()) {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "publishAt must be in the future and before deadline"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
 
-	if _, err := s.db.UpdateTender(r.Context(), tender); err != nil {
+	err = s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if _, err := db.UpdateTender(r.Context(), tender, model.EventTenderEdited); err != nil {
+			return err
+		}
+		return s.audit(db, r, username, tenderAuditEntry(model.AuditTenderEdit, tender), &before, tender)
+	})
+	if err != nil {
 		if errors.Is(database.ErrTenderNotFound, err) {
 			w.WriteHeader(http.StatusNotFound)
 			resp := ErrResponse{Reason: "tender not found"}
@@ -487,7 +559,15 @@ func (s *Server) rollbackVersion(w http.ResponseWriter, r *http.Request) {
 		_ = json.NewEncoder(w).Encode(resp)
 		return
 	}
-	newTender, err := s.db.RollbackTender(r.Context(), tenderId, ver)
+	var newTender *model.Tender
+	err = s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		var err error
+		newTender, err = db.RollbackTender(r.Context(), tenderId, ver, model.EventTenderEdited)
+		if err != nil {
+			return err
+		}
+		return s.audit(db, r, username, tenderAuditEntry(model.AuditTenderRollback, newTender), tender, newTender)
+	})
 	if err != nil {
 		if errors.Is(database.ErrTenderNotFound, err) {
 			w.WriteHeader(http.StatusNotFound)
@@ -506,6 +586,126 @@ func (s *Server) rollbackVersion(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// cloneTender copies the latest or the requested version of a tender into a
+// new draft owned by the caller's organization, together with its tender
+// attachments and lots.
+func (s *Server) cloneTender(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	tenderId := mux.Vars(r)["tenderId"]
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUuid(tenderId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	var req TenderCloneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		slog.Warn("error decoding body", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "error decoding body"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if len(req.Name) > 100 {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "name is too long. Max length is 100"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if req.OrganizationId != "" && !validator.ValidateUuid(req.OrganizationId) {
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	tender, ok := s.getTender(w, r, tenderId)
+	if !ok {
+		return
+	}
+	if !s.checkTenderAvailable(w, r, tender, employee) {
+		return
+	}
+	version, ok := parseVersion(w, r, tender.Version)
+	if !ok {
+		return
+	}
+	source := tender
+	if version != tender.Version {
+		var err error
+		source, err = s.db.GetTenderByIdAndVersion(r.Context(), tender.ID, version)
+		if err != nil {
+			if errors.Is(err, database.ErrTenderNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+				resp := ErrResponse{Reason: "tender version not found"}
+				_ = json.NewEncoder(w).Encode(resp)
+				return
+			}
+			slog.Warn("error getting tender by id and version", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			resp := ErrResponse{Reason: "error getting tender by id and version"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+	}
+	organizationId := req.OrganizationId
+	if organizationId == "" {
+		organizationId = source.OrganizationID
+	}
+	isInOrganization, err := s.db.IsEmployeeInOrganization(r.Context(), username, organizationId)
+	if err != nil {
+		slog.Warn("error checking employee in organization", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error checking employee in organization"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if !isInOrganization {
+		w.WriteHeader(http.StatusForbidden)
+		resp := ErrResponse{Reason: "employee is not in organization"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	clone := &model.Tender{
+		Name:           source.Name,
+		Description:    source.Description,
+		ServiceType:    source.ServiceType,
+		Status:         model.TenderCreated,
+		OrganizationID: organizationId,
+		CreatorID:      employee.ID,
+		Budget:         source.Budget,
+		Visibility:     source.Visibility,
+	}
+	if req.Name != "" {
+		clone.Name = req.Name
+	}
+	if !s.validateServiceType(w, r, clone.ServiceType, false) {
+		return
+	}
+	// A deadline that has already passed would make the draft unpublishable,
+	// and the publication schedule belongs to the original tender.
+	if IsValidDeadline(source.Deadline, time.Now()) {
+		clone.Deadline = source.Deadline
+	}
+	err = s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if _, err := db.CloneTender(r.Context(), source.ID, source.Version, clone, model.EventTenderCreated); err != nil {
+			return err
+		}
+		return s.audit(db, r, username, tenderAuditEntry(model.AuditTenderClone, clone), source, clone)
+	})
+	if err != nil {
+		slog.Warn("error cloning tender", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error cloning tender"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := tenderToResponse(clone)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
 func tendersToResponse(tenders []model.Tender) []*TenderResponse {
 	return lo.Map(tenders, func(tender model.Tender, _ int) *TenderResponse {
 		return tenderToResponse(&tender)
@@ -519,7 +719,41 @@ func requestToTender(req *TenderRequest, creatorId string) *model.Tender {
 		ServiceType:    req.ServiceType,
 		OrganizationID: req.OrganizationId,
 		CreatorID:      creatorId,
+		Deadline:       utcTime(req.Deadline),
+		PublishAt:      utcTime(req.PublishAt),
+		Budget:         requestToMoney(req.Budget),
+		Visibility:     model.TenderVisibility(req.Visibility),
+	}
+}
+
+func requestToMoney(req *MoneyRequest) *model.Money {
+	if req == nil {
+		return nil
+	}
+	return &model.Money{Amount: req.Amount.String(), Currency: req.Currency}
+}
+
+func moneyToResponse(money *model.Money) *MoneyResponse {
+	if money == nil {
+		return nil
+	}
+	return &MoneyResponse{Amount: json.Number(money.Amount), Currency: money.Currency}
+}
+
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+func jsonTime(t *time.Time) *JSONTime {
+	if t == nil {
+		return nil
 	}
+	jt := JSONTime(*t)
+	return &jt
 }
 
 func tenderToResponse(tender *model.Tender) *TenderResponse {
@@ -531,5 +765,9 @@ func tenderToResponse(tender *model.Tender) *TenderResponse {
 		ServiceType: tender.ServiceType,
 		Version:     tender.Version,
 		CreatedAt:   JSONTime(tender.CreatedAt),
+		Deadline:    jsonTime(tender.Deadline),
+		PublishAt:   jsonTime(tender.PublishAt),
+		Budget:      moneyToResponse(tender.Budget),
+		Visibility:  string(tender.Visibility),
 	}
 }
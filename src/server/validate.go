@@ -4,19 +4,31 @@ import (
 	"context"
 	"encoding/json"
 	"github.com/google/uuid"
+	"golang.org/x/text/currency"
 	"log/slog"
 	"net/http"
+	"net/mail"
+	"net/url"
+	"regexp"
 	"slices"
 	"strconv"
+	"strings"
+	"time"
 	"zadanie-6105/database"
 	"zadanie-6105/model"
 )
 
 const (
-	MaxUsernameLength = 50
+	MaxUsernameLength    = 50
+	MaxSearchQueryLength = 200
+	MaxEmailLength       = 254
+	MaxBidNameLength     = 100
+	MaxBidDescLength     = 500
+	MaxBidTermsLength    = 2000
 )
 
-var availableServiceTypes = []string{"Construction", "Delivery", "Manufacture"}
+var amountRegexp = regexp.MustCompile(`^\d{1,15}(\.\d{1,2})?$`)
+
 var availableTenderStatuses = []model.TenderStatus{model.TenderCreated, model.TenderPublished, model.TenderClosed}
 var availableBidStatuses = []model.BidStatus{model.BidCreated, model.BidPublished, model.BidCanceled}
 
@@ -24,12 +36,94 @@ func IsValidTenderStatus(status string) bool {
 	return slices.Contains(availableTenderStatuses, model.TenderStatus(status))
 }
 
-func IsValidServiceType(serviceType string) bool {
-	return slices.Contains(availableServiceTypes, serviceType)
+func IsValidBidStatus(status string) bool {
+	return slices.Contains(availableBidStatuses, model.BidStatus(status))
+}
+
+func IsValidBidDecision(decision string) bool {
+	return decision == string(model.BidApproved) || decision == string(model.BidRejected)
+}
+
+func IsValidAuthorType(authorType string) bool {
+	return authorType == string(model.AuthorUser) || authorType == string(model.AuthorOrganization)
+}
+
+func IsValidUuid(value string) bool {
+	_, err := uuid.Parse(value)
+	return err == nil
+}
+
+func IsValidTenderVisibility(visibility string) bool {
+	return visibility == string(model.TenderPublic) || visibility == string(model.TenderInviteOnly)
+}
+
+// IsValidWebhookURL accepts absolute http and https URLs.
+func IsValidWebhookURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// IsValidEmail accepts a bare address such as user@example.com.
+func IsValidEmail(value string) bool {
+	if len(value) > MaxEmailLength {
+		return false
+	}
+	address, err := mail.ParseAddress(value)
+	return err == nil && address.Address == value
+}
+
+func IsValidEventTypes(eventTypes []string) bool {
+	if len(eventTypes) == 0 {
+		return false
+	}
+	for _, eventType := range eventTypes {
+		if !slices.Contains(model.EventTypes, model.EventType(eventType)) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsTenderAvailable reports whether the employee may see the tender. Invited
+// means the employee is responsible for the tender's organization or for one
+// of the organizations invited to it; it only matters for invite-only tenders.
+func IsTenderAvailable(t *model.Tender, employee *model.Employee, invited bool) bool {
+	if t.CreatorID == employee.ID {
+		return true
+	}
+	return t.Status == model.TenderPublished && (t.Visibility != model.TenderInviteOnly || invited)
 }
 
-func IsTenderAvailable(t *model.Tender, employee *model.Employee) bool {
-	return t.Status == model.TenderPublished || t.CreatorID == employee.ID
+func IsTenderAcceptingBids(t *model.Tender, invited bool, now time.Time) bool {
+	return t.Status == model.TenderPublished && (t.Visibility != model.TenderInviteOnly || invited) &&
+		(t.Deadline == nil || now.Before(*t.Deadline))
+}
+
+func IsValidDeadline(deadline *time.Time, now time.Time) bool {
+	return deadline == nil || deadline.After(now)
+}
+
+func IsValidPublishAt(publishAt, deadline *time.Time, now time.Time) bool {
+	if publishAt == nil {
+		return true
+	}
+	return publishAt.After(now) && (deadline == nil || publishAt.Before(*deadline))
+}
+
+func IsValidAmount(amount string) bool {
+	return amountRegexp.MatchString(amount)
+}
+
+func IsValidCurrency(code string) bool {
+	if len(code) != 3 || strings.ToUpper(code) != code {
+		return false
+	}
+	_, err := currency.ParseISO(code)
+	return err == nil
+}
+
+func IsValidMoney(money *MoneyRequest) bool {
+	return money == nil || (IsValidAmount(money.Amount.String()) && IsValidCurrency(money.Currency))
 }
 
 type Validator struct {
@@ -73,8 +167,7 @@ func (v *Validator) ValidateUsername(username string) bool {
 }
 
 func (v *Validator) ValidateUuid(uuidValue string) bool {
-	_, err := uuid.Parse(uuidValue)
-	if err != nil {
+	if !IsValidUuid(uuidValue) {
 		v.w.WriteHeader(http.StatusBadRequest)
 		resp := ErrResponse{Reason: "tender id is not valid"}
 		_ = json.NewEncoder(v.w).Encode(resp)
@@ -131,3 +224,25 @@ func (v *Validator) ValidatePagination(limit, offset string) (bool, int, int) {
 	}
 	return true, limitInt, offsetInt
 }
+
+// ValidateCursor decodes the keyset cursor. An offset would skip rows past
+// the cursor position, so the two can not be combined.
+func (v *Validator) ValidateCursor(after string, offset int) (bool, *database.TenderCursor) {
+	if after == "" {
+		return true, nil
+	}
+	if offset != 0 {
+		v.w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "after can not be combined with offset"}
+		_ = json.NewEncoder(v.w).Encode(resp)
+		return false, nil
+	}
+	cursor, err := decodeCursor(after)
+	if err != nil {
+		v.w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "after is not a valid cursor"}
+		_ = json.NewEncoder(v.w).Encode(resp)
+		return false, nil
+	}
+	return true, cursor
+}
@@ -0,0 +1,384 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/samber/lo"
+	"log/slog"
+	"net/http"
+	"zadanie-6105/database"
+	"zadanie-6105/model"
+)
+
+const (
+	MaxWebhookURLLength    = 2000
+	MinWebhookSecretLength = 16
+	MaxWebhookSecretLength = 200
+)
+
+func (s *Server) organizationWebhooks(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	organizationId := mux.Vars(r)["organizationId"]
+	username := r.URL.Query().Get("username")
+	ok, limit, offset := validator.ValidatePagination(r.URL.Query().Get("limit"), r.URL.Query().Get("offset"))
+	if !ok {
+		return
+	}
+	if !validator.ValidateUuid(organizationId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	if !s.checkOrganizationResponsible(w, r, username, organizationId) {
+		return
+	}
+	hooks, err := s.db.GetOrganizationWebhooks(r.Context(), limit, offset, organizationId)
+	if err != nil {
+		slog.Warn("error getting organization webhooks", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting webhooks"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := lo.Map(hooks, func(hook model.Webhook, _ int) *WebhookResponse {
+		return webhookToResponse(&hook, false)
+	})
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+// newWebhook subscribes the organization to events. The secret is generated
+// when the request does not provide one and is returned only in this response.
+func (s *Server) newWebhook(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	organizationId := mux.Vars(r)["organizationId"]
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUuid(organizationId) {
+		return
+	}
+	if !validator.ValidateUsername(username) {
+		return
+	}
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Warn("error decoding body", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "error decoding body"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if req.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			slog.Warn("error generating webhook secret", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			resp := ErrResponse{Reason: "error generating webhook secret"}
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		req.Secret = secret
+	}
+	if !validateWebhookRequest(w, &req) {
+		return
+	}
+	employee, ok := s.getEmployee(w, r, username)
+	if !ok {
+		return
+	}
+	if !s.checkOrganizationResponsible(w, r, username, organizationId) {
+		return
+	}
+	hook := &model.Webhook{
+		OrganizationID: organizationId,
+		URL:            req.URL,
+		EventTypes:     toEventTypes(req.EventTypes),
+		Secret:         req.Secret,
+		Active:         req.Active == nil || *req.Active,
+		CreatorID:      employee.ID,
+	}
+	err := s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if _, err := db.SaveWebhook(r.Context(), hook); err != nil {
+			return err
+		}
+		return s.audit(db, r, username, webhookAuditEntry(model.AuditWebhookCreate, hook), nil, hook)
+	})
+	if err != nil {
+		s.writeWebhookError(w, err, "error saving webhook")
+		return
+	}
+	resp := webhookToResponse(hook, true)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) getWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, ok := s.webhookForResponsible(w, r)
+	if !ok {
+		return
+	}
+	resp := webhookToResponse(hook, false)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+// editWebhook updates the given fields. A new secret replaces the old one
+// immediately, so receivers should accept both while rotating.
+func (s *Server) editWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, ok := s.webhookForResponsible(w, r)
+	if !ok {
+		return
+	}
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		slog.Warn("error decoding body", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: "error decoding body"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if req.URL == "" {
+		req.URL = hook.URL
+	}
+	if req.EventTypes == nil {
+		req.EventTypes = lo.Map(hook.EventTypes, func(eventType model.EventType, _ int) string {
+			return string(eventType)
+		})
+	}
+	if req.Secret == "" {
+		req.Secret = hook.Secret
+	}
+	if !validateWebhookRequest(w, &req) {
+		return
+	}
+	before := *hook
+	hook.URL = req.URL
+	hook.EventTypes = toEventTypes(req.EventTypes)
+	hook.Secret = req.Secret
+	if req.Active != nil {
+		hook.Active = *req.Active
+	}
+	err := s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if _, err := db.UpdateWebhook(r.Context(), hook); err != nil {
+			return err
+		}
+		return s.audit(db, r, r.URL.Query().Get("username"), webhookAuditEntry(model.AuditWebhookEdit, hook), &before, hook)
+	})
+	if err != nil {
+		s.writeWebhookError(w, err, "error updating webhook")
+		return
+	}
+	resp := webhookToResponse(hook, false)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, ok := s.webhookForResponsible(w, r)
+	if !ok {
+		return
+	}
+	err := s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		if err := db.DeleteWebhook(r.Context(), hook.ID); err != nil {
+			return err
+		}
+		return s.audit(db, r, r.URL.Query().Get("username"), webhookAuditEntry(model.AuditWebhookDelete, hook), hook, nil)
+	})
+	if err != nil {
+		s.writeWebhookError(w, err, "error deleting webhook")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) webhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	ok, limit, offset := validator.ValidatePagination(r.URL.Query().Get("limit"), r.URL.Query().Get("offset"))
+	if !ok {
+		return
+	}
+	hook, ok := s.webhookForResponsible(w, r)
+	if !ok {
+		return
+	}
+	deliveries, err := s.db.GetWebhookDeliveries(r.Context(), limit, offset, hook.ID)
+	if err != nil {
+		slog.Warn("error getting webhook deliveries", "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: "error getting webhook deliveries"}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp := lo.Map(deliveries, func(delivery model.WebhookDelivery, _ int) *WebhookDeliveryResponse {
+		return deliveryToResponse(&delivery)
+	})
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+// redeliverWebhook queues the payload of a past delivery again as a new
+// delivery, whatever the outcome of the original one was.
+func (s *Server) redeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	validator := NewValidator(w, r, s.db)
+	deliveryId := mux.Vars(r)["deliveryId"]
+	if !validator.ValidateUuid(deliveryId) {
+		return
+	}
+	hook, ok := s.webhookForResponsible(w, r)
+	if !ok {
+		return
+	}
+	delivery, err := s.db.GetWebhookDeliveryByID(r.Context(), deliveryId)
+	if err == nil && delivery.WebhookID != hook.ID {
+		err = database.ErrDeliveryNotFound
+	}
+	if err != nil {
+		s.writeWebhookError(w, err, "error getting webhook delivery by id")
+		return
+	}
+	var redelivery *model.WebhookDelivery
+	err = s.db.InTx(r.Context(), func(db database.DbConnector) error {
+		var err error
+		redelivery, err = db.RedeliverWebhookDelivery(r.Context(), delivery.ID)
+		if err != nil {
+			return err
+		}
+		return s.audit(db, r, r.URL.Query().Get("username"), webhookAuditEntry(model.AuditWebhookRedeliver, hook), delivery, redelivery)
+	})
+	if err != nil {
+		s.writeWebhookError(w, err, "error redelivering webhook")
+		return
+	}
+	resp := deliveryToResponse(redelivery)
+	_ = json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(http.StatusOK)
+}
+
+// webhookForResponsible loads the webhook from the route and checks that the
+// user is responsible for the webhook's organization.
+func (s *Server) webhookForResponsible(w http.ResponseWriter, r *http.Request) (*model.Webhook, bool) {
+	validator := NewValidator(w, r, s.db)
+	webhookId := mux.Vars(r)["webhookId"]
+	username := r.URL.Query().Get("username")
+	if !validator.ValidateUuid(webhookId) {
+		return nil, false
+	}
+	if !validator.ValidateUsername(username) {
+		return nil, false
+	}
+	hook, err := s.db.GetWebhookByID(r.Context(), webhookId)
+	if err != nil {
+		s.writeWebhookError(w, err, "error getting webhook by id")
+		return nil, false
+	}
+	if !s.checkOrganizationResponsible(w, r, username, hook.OrganizationID) {
+		return nil, false
+	}
+	return hook, true
+}
+
+func (s *Server) writeWebhookError(w http.ResponseWriter, err error, reason string) {
+	switch {
+	case errors.Is(err, database.ErrWebhookNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		resp := ErrResponse{Reason: "webhook not found"}
+		_ = json.NewEncoder(w).Encode(resp)
+	case errors.Is(err, database.ErrDeliveryNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		resp := ErrResponse{Reason: "webhook delivery not found"}
+		_ = json.NewEncoder(w).Encode(resp)
+	case errors.Is(err, database.ErrOrganizationNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		resp := ErrResponse{Reason: "organization not found"}
+		_ = json.NewEncoder(w).Encode(resp)
+	default:
+		slog.Warn(reason, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		resp := ErrResponse{Reason: reason}
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func validateWebhookRequest(w http.ResponseWriter, req *WebhookRequest) bool {
+	reason := ""
+	switch {
+	case len(req.URL) > MaxWebhookURLLength || !IsValidWebhookURL(req.URL):
+		reason = "url must be an absolute http or https URL"
+	case !IsValidEventTypes(req.EventTypes):
+		reason = "eventTypes must be a non-empty list of known event types"
+	case len(req.Secret) < MinWebhookSecretLength || len(req.Secret) > MaxWebhookSecretLength:
+		reason = "secret length must be between 16 and 200"
+	}
+	if reason != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		resp := ErrResponse{Reason: reason}
+		_ = json.NewEncoder(w).Encode(resp)
+		return false
+	}
+	return true
+}
+
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+func toEventTypes(eventTypes []string) []model.EventType {
+	return lo.Uniq(lo.Map(eventTypes, func(eventType string, _ int) model.EventType {
+		return model.EventType(eventType)
+	}))
+}
+
+func webhookAuditEntry(action string, hook *model.Webhook) *model.AuditEntry {
+	return &model.AuditEntry{
+		Action:         action,
+		EntityType:     model.EntityWebhook,
+		EntityID:       hook.ID,
+		OrganizationID: &hook.OrganizationID,
+	}
+}
+
+func webhookToResponse(hook *model.Webhook, withSecret bool) *WebhookResponse {
+	resp := &WebhookResponse{
+		ID:             hook.ID,
+		OrganizationID: hook.OrganizationID,
+		URL:            hook.URL,
+		EventTypes: lo.Map(hook.EventTypes, func(eventType model.EventType, _ int) string {
+			return string(eventType)
+		}),
+		Active:    hook.Active,
+		CreatedAt: JSONTime(hook.CreatedAt),
+		UpdatedAt: JSONTime(hook.UpdatedAt),
+	}
+	if withSecret {
+		resp.Secret = hook.Secret
+	}
+	return resp
+}
+
+func deliveryToResponse(d *model.WebhookDelivery) *WebhookDeliveryResponse {
+	resp := &WebhookDeliveryResponse{
+		ID:             d.ID,
+		WebhookID:      d.WebhookID,
+		EventID:        d.EventID,
+		EventType:      string(d.EventType),
+		Payload:        d.Payload,
+		RedeliveryOf:   d.RedeliveryOf,
+		Status:         string(d.Status),
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		CreatedAt:      JSONTime(d.CreatedAt),
+		DeliveredAt:    jsonTime(d.DeliveredAt),
+	}
+	if d.Status == model.WebhookDeliveryPending {
+		resp.NextAttemptAt = jsonTime(&d.NextAttemptAt)
+	}
+	return resp
+}
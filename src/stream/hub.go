@@ -0,0 +1,213 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+	"time"
+	"zadanie-6105/config"
+	"zadanie-6105/database"
+	"zadanie-6105/health"
+	"zadanie-6105/model"
+)
+
+const (
+	fetchBatchSize     = 500
+	subscriptionBuffer = 64
+	listenRetryDelay   = 5 * time.Second
+)
+
+// Event is a tender outbox event with its decoded payload.
+type Event struct {
+	*model.OutboxEvent
+	Tender model.TenderEventPayload
+}
+
+// Filter narrows a subscription. Empty fields match everything.
+type Filter struct {
+	ServiceTypes    []string
+	OrganizationIDs []string
+}
+
+// Match reports whether the event passes the filter.
+func (f *Filter) Match(e *Event) bool {
+	return (len(f.ServiceTypes) == 0 || slices.Contains(f.ServiceTypes, e.Tender.ServiceType)) &&
+		(len(f.OrganizationIDs) == 0 || slices.Contains(f.OrganizationIDs, e.Tender.OrganizationID))
+}
+
+type Subscription struct {
+	C      <-chan *Event
+	ch     chan *Event
+	filter Filter
+}
+
+// Hub fans tender events out to in-process subscribers. Every instance of the
+// service listens for outbox inserts through Postgres LISTEN/NOTIFY, so a
+// subscriber sees events written by any instance. Events are read by id;
+// outbox ids are assigned in commit order (see outbox_event_order in
+// db/init.sql), so once an id has been read no smaller id can appear later
+// and clients resuming from Last-Event-ID miss nothing.
+type Hub struct {
+	health.Heartbeat
+	db           database.DbConnector
+	pollInterval time.Duration
+	mu           sync.Mutex
+	subs         map[*Subscription]struct{}
+	lastID       int64
+	closed       bool
+}
+
+func NewHub(cfg *config.Config, db database.DbConnector) *Hub {
+	return &Hub{
+		db:           db,
+		pollInterval: cfg.StreamPollInterval,
+		subs:         make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe registers a subscriber and returns the id of the last event
+// broadcast before it. The subscription receives only events after that id;
+// its channel is closed when the hub stops or the subscriber falls behind.
+func (h *Hub) Subscribe(filter Filter) (*Subscription, int64) {
+	ch := make(chan *Event, subscriptionBuffer)
+	sub := &Subscription{C: ch, ch: ch, filter: filter}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(ch)
+		return sub, h.lastID
+	}
+	h.subs[sub] = struct{}{}
+	return sub, h.lastID
+}
+
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
+
+func (h *Hub) Run(ctx context.Context) {
+	lastID, err := h.db.GetLastOutboxEventID(ctx)
+	if err != nil {
+		slog.Warn("error getting last outbox event id", "error", err)
+	}
+	h.mu.Lock()
+	h.lastID = lastID
+	h.mu.Unlock()
+
+	notify := make(chan struct{}, 1)
+	go h.listen(ctx, notify)
+	slog.Debug("start tender stream hub", "last_event_id", lastID)
+	ticker := time.NewTicker(h.pollInterval)
+	defer ticker.Stop()
+	for {
+		h.Beat()
+		select {
+		case <-ctx.Done():
+			h.Close()
+			slog.Debug("tender stream hub stopped")
+			return
+		case <-notify:
+		case <-ticker.C:
+		}
+		h.fetch(ctx)
+	}
+}
+
+// listen keeps a LISTEN connection open and reconnects after failures. The
+// poll ticker in Run covers events inserted while it is reconnecting.
+func (h *Hub) listen(ctx context.Context, notify chan<- struct{}) {
+	for {
+		err := h.db.ListenOutboxEvents(ctx, func() {
+			select {
+			case notify <- struct{}{}:
+			default:
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("outbox listener failed", "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+func (h *Hub) fetch(ctx context.Context) {
+	for {
+		h.mu.Lock()
+		lastID := h.lastID
+		h.mu.Unlock()
+		events, err := h.db.GetOutboxEvents(ctx, lastID, math.MaxInt64, fetchBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Warn("error getting outbox events", "error", err)
+			}
+			return
+		}
+		h.broadcast(events)
+		if len(events) < fetchBatchSize {
+			return
+		}
+	}
+}
+
+func (h *Hub) broadcast(events []model.OutboxEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := range events {
+		h.lastID = events[i].ID
+		event, ok := NewEvent(&events[i])
+		if !ok {
+			continue
+		}
+		for sub := range h.subs {
+			if !sub.filter.Match(event) {
+				continue
+			}
+			select {
+			case sub.ch <- event:
+			default:
+				slog.Debug("dropping slow stream subscriber")
+				delete(h.subs, sub)
+				close(sub.ch)
+			}
+		}
+	}
+}
+
+// Close ends every subscription and rejects new ones. Run closes the hub when
+// it stops; the HTTP server closes it earlier, when it shuts down.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subs {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// NewEvent decodes a tender outbox event. It reports false for events of
+// other aggregates and for payloads that cannot be decoded.
+func NewEvent(e *model.OutboxEvent) (*Event, bool) {
+	if e.AggregateType != model.EntityTender {
+		return nil, false
+	}
+	event := &Event{OutboxEvent: e}
+	if err := json.Unmarshal(e.Payload, &event.Tender); err != nil {
+		slog.Warn("error decoding tender event", "error", err, "id", e.ID)
+		return nil, false
+	}
+	return event, true
+}
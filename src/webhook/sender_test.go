@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+	"zadanie-6105/model"
+)
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// newReceiver starts a webhook receiver that answers with the given status
+// codes in turn, repeating the last one, and records every request.
+func newReceiver(t *testing.T, statuses ...int) (*httptest.Server, *[]receivedRequest) {
+	t.Helper()
+	var received []receivedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, receivedRequest{header: r.Header.Clone(), body: body})
+		w.WriteHeader(statuses[min(len(received), len(statuses))-1])
+	}))
+	t.Cleanup(server.Close)
+	return server, &received
+}
+
+func testDelivery(id string) *model.WebhookDelivery {
+	return &model.WebhookDelivery{
+		ID:            id,
+		WebhookID:     "w0000000-0000-0000-0000-000000000001",
+		EventID:       7,
+		EventType:     model.EventTenderPublished,
+		Payload:       []byte(`{"id":7,"type":"tender.published","data":{"name":"Roadworks"}}`),
+		Status:        model.WebhookDeliveryPending,
+		NextAttemptAt: time.Now(),
+	}
+}
+
+func TestSenderSignsPayload(t *testing.T) {
+	server, received := newReceiver(t, http.StatusNoContent)
+	hook := &model.Webhook{ID: "w0000000-0000-0000-0000-000000000001", URL: server.URL, Secret: "s3cret"}
+	delivery := testDelivery("d0000000-0000-0000-0000-000000000001")
+	sender := NewSender(server.Client())
+	sender.now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	status, err := sender.Send(context.Background(), hook, delivery)
+	if err != nil || status != http.StatusNoContent {
+		t.Fatalf("Send() = %d, %v; want 204, nil", status, err)
+	}
+	if len(*received) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(*received))
+	}
+	req := (*received)[0]
+	if string(req.body) != string(delivery.Payload) {
+		t.Errorf("body = %s, want the delivery payload", req.body)
+	}
+	if got := req.header.Get(TimestampHeader); got != "1700000000" {
+		t.Errorf("%s = %q, want 1700000000", TimestampHeader, got)
+	}
+	want := Sign("s3cret", 1700000000, delivery.Payload)
+	if got := req.header.Get(SignatureHeader); got != want {
+		t.Errorf("%s = %q, want %q", SignatureHeader, got, want)
+	}
+	timestamp, _ := strconv.ParseInt(req.header.Get(TimestampHeader), 10, 64)
+	if !Verify("s3cret", timestamp, req.body, req.header.Get(SignatureHeader)) {
+		t.Error("receiver cannot verify the signature")
+	}
+	if Verify("other", timestamp, req.body, req.header.Get(SignatureHeader)) {
+		t.Error("signature verifies with a different secret")
+	}
+	if got := req.header.Get(EventHeader); got != string(model.EventTenderPublished) {
+		t.Errorf("%s = %q", EventHeader, got)
+	}
+	if got := req.header.Get(DeliveryHeader); got != delivery.ID {
+		t.Errorf("%s = %q, want %q", DeliveryHeader, got, delivery.ID)
+	}
+}
+
+func TestSenderRejectsNon2xx(t *testing.T) {
+	server, _ := newReceiver(t, http.StatusBadGateway)
+	hook := &model.Webhook{URL: server.URL, Secret: "s3cret"}
+
+	status, err := NewSender(server.Client()).Send(context.Background(), hook, testDelivery("d1"))
+	if err == nil || status != http.StatusBadGateway {
+		t.Fatalf("Send() = %d, %v; want 502 and an error", status, err)
+	}
+}
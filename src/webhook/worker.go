@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"context"
+	"github.com/samber/lo"
+	"log/slog"
+	"net/http"
+	"time"
+	"zadanie-6105/config"
+	"zadanie-6105/database"
+	"zadanie-6105/health"
+	"zadanie-6105/model"
+	"zadanie-6105/outbox"
+)
+
+const (
+	deliveryBatchSize = 100
+	deliveryLockName  = "webhook_worker"
+)
+
+// Sink fans outbox events out into webhook deliveries. It only queues them;
+// the Worker sends them, so a slow receiver never blocks the outbox.
+type Sink struct {
+	db database.DbConnector
+}
+
+func NewSink(db database.DbConnector) *Sink {
+	return &Sink{db: db}
+}
+
+func (s *Sink) Name() string {
+	return "webhook"
+}
+
+func (s *Sink) Deliver(ctx context.Context, event *model.OutboxEvent) error {
+	queued, err := s.db.SaveWebhookDeliveries(ctx, event.ID)
+	if err != nil {
+		return err
+	}
+	if queued > 0 {
+		slog.Debug("webhook deliveries queued", "event_id", event.ID, "count", queued)
+	}
+	return nil
+}
+
+// Worker sends due webhook deliveries and retries failed ones with
+// exponential backoff until maxAttempts is reached.
+type Worker struct {
+	health.Heartbeat
+	db          database.DbConnector
+	sender      *Sender
+	interval    time.Duration
+	maxAttempts int
+	backoff     time.Duration
+}
+
+func NewWorker(cfg *config.Config, db database.DbConnector) *Worker {
+	return &Worker{
+		db:          db,
+		sender:      NewSender(&http.Client{Timeout: cfg.WebhookTimeout}),
+		interval:    cfg.WebhookDeliveryInterval,
+		maxAttempts: cfg.WebhookMaxAttempts,
+		backoff:     cfg.WebhookRetryBackoff,
+	}
+}
+
+func (w *Worker) Run(ctx context.Context) {
+	slog.Debug("start webhook worker", "interval", w.interval)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.tick(ctx)
+		w.Beat()
+		select {
+		case <-ctx.Done():
+			slog.Debug("webhook worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) tick(ctx context.Context) {
+	_, err := w.db.RunExclusive(ctx, deliveryLockName, func(ctx context.Context) error {
+		deliveries, err := w.db.GetDueWebhookDeliveries(ctx, time.Now(), deliveryBatchSize)
+		if err != nil {
+			return err
+		}
+		hooks := make(map[string]*model.Webhook)
+		for i := range deliveries {
+			if ctx.Err() != nil {
+				return nil
+			}
+			delivery := &deliveries[i]
+			hook, ok := hooks[delivery.WebhookID]
+			if !ok {
+				if hook, err = w.db.GetWebhookByID(ctx, delivery.WebhookID); err != nil {
+					return err
+				}
+				hooks[delivery.WebhookID] = hook
+			}
+			if err = w.deliver(ctx, hook, delivery); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		slog.Warn("error sending webhook deliveries", "error", err)
+	}
+}
+
+func (w *Worker) deliver(ctx context.Context, hook *model.Webhook, delivery *model.WebhookDelivery) error {
+	statusCode, err := w.sender.Send(ctx, hook, delivery)
+	if err != nil && ctx.Err() != nil {
+		return nil
+	}
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastStatusCode = nil
+	if statusCode != 0 {
+		delivery.LastStatusCode = &statusCode
+	}
+	switch {
+	case err == nil:
+		delivery.Status = model.WebhookDeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = nil
+	case delivery.Attempts >= w.maxAttempts:
+		slog.Warn("webhook delivery failed", "id", delivery.ID, "webhook_id", hook.ID,
+			"attempts", delivery.Attempts, "error", err)
+		delivery.Status = model.WebhookDeliveryFailed
+		delivery.LastError = lo.ToPtr(err.Error())
+	default:
+		delivery.NextAttemptAt = now.Add(outbox.RetryDelay(w.backoff, delivery.Attempts))
+		delivery.LastError = lo.ToPtr(err.Error())
+	}
+	return w.db.UpdateWebhookDelivery(ctx, delivery)
+}
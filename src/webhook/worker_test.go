@@ -0,0 +1,141 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+	"zadanie-6105/config"
+	"zadanie-6105/database"
+	"zadanie-6105/model"
+	"zadanie-6105/outbox"
+)
+
+type fakeWebhookDb struct {
+	database.DbConnector
+	hook       *model.Webhook
+	deliveries []*model.WebhookDelivery
+}
+
+func (db *fakeWebhookDb) RunExclusive(ctx context.Context, _ string, fn func(ctx context.Context) error) (bool, error) {
+	return true, fn(ctx)
+}
+
+func (db *fakeWebhookDb) GetDueWebhookDeliveries(_ context.Context, now time.Time, limit int) ([]model.WebhookDelivery, error) {
+	var due []model.WebhookDelivery
+	for _, delivery := range db.deliveries {
+		if delivery.Status == model.WebhookDeliveryPending && !delivery.NextAttemptAt.After(now) && len(due) < limit {
+			due = append(due, *delivery)
+		}
+	}
+	return due, nil
+}
+
+func (db *fakeWebhookDb) GetWebhookByID(_ context.Context, _ string) (*model.Webhook, error) {
+	return db.hook, nil
+}
+
+func (db *fakeWebhookDb) UpdateWebhookDelivery(_ context.Context, d *model.WebhookDelivery) error {
+	for _, delivery := range db.deliveries {
+		if delivery.ID == d.ID {
+			*delivery = *d
+		}
+	}
+	return nil
+}
+
+func (db *fakeWebhookDb) RedeliverWebhookDelivery(_ context.Context, id string) (*model.WebhookDelivery, error) {
+	for _, delivery := range db.deliveries {
+		if delivery.ID == id {
+			redelivery := testDelivery(id + "-redelivery")
+			redelivery.Payload = delivery.Payload
+			redelivery.RedeliveryOf = &delivery.ID
+			db.deliveries = append(db.deliveries, redelivery)
+			return redelivery, nil
+		}
+	}
+	return nil, database.ErrDeliveryNotFound
+}
+
+func newTestWorker(db database.DbConnector) *Worker {
+	return NewWorker(&config.Config{
+		WebhookDeliveryInterval: time.Second,
+		WebhookMaxAttempts:      3,
+		WebhookRetryBackoff:     30 * time.Second,
+		WebhookTimeout:          time.Second,
+	}, db)
+}
+
+func TestWorkerRetriesWithBackoffUntilFailed(t *testing.T) {
+	server, received := newReceiver(t, http.StatusInternalServerError)
+	delivery := testDelivery("d0000000-0000-0000-0000-000000000001")
+	db := &fakeWebhookDb{
+		hook:       &model.Webhook{ID: delivery.WebhookID, URL: server.URL, Secret: "s3cret"},
+		deliveries: []*model.WebhookDelivery{delivery},
+	}
+	w := newTestWorker(db)
+
+	for attempt := 1; attempt < 3; attempt++ {
+		before := time.Now()
+		w.tick(context.Background())
+		if delivery.Attempts != attempt || delivery.Status != model.WebhookDeliveryPending {
+			t.Fatalf("after attempt %d delivery is %s with %d attempts", attempt, delivery.Status, delivery.Attempts)
+		}
+		want := outbox.RetryDelay(30*time.Second, attempt)
+		if delay := delivery.NextAttemptAt.Sub(before); delay < want || delay > want+time.Second {
+			t.Errorf("after attempt %d next attempt in %v, want %v", attempt, delay, want)
+		}
+		if delivery.LastStatusCode == nil || *delivery.LastStatusCode != http.StatusInternalServerError {
+			t.Errorf("after attempt %d last status code = %v, want 500", attempt, delivery.LastStatusCode)
+		}
+		// Not due yet: the next tick must not send it.
+		w.tick(context.Background())
+		if len(*received) != attempt {
+			t.Fatalf("receiver got %d requests before the backoff elapsed, want %d", len(*received), attempt)
+		}
+		delivery.NextAttemptAt = time.Now()
+	}
+
+	w.tick(context.Background())
+	if delivery.Status != model.WebhookDeliveryFailed || delivery.Attempts != 3 {
+		t.Errorf("delivery is %s with %d attempts, want Failed after 3", delivery.Status, delivery.Attempts)
+	}
+}
+
+func TestWorkerSendsRedelivery(t *testing.T) {
+	server, received := newReceiver(t, http.StatusInternalServerError, http.StatusOK)
+	original := testDelivery("d0000000-0000-0000-0000-000000000001")
+	original.Status = model.WebhookDeliveryFailed
+	original.Attempts = 3
+	db := &fakeWebhookDb{
+		hook:       &model.Webhook{ID: original.WebhookID, URL: server.URL, Secret: "s3cret"},
+		deliveries: []*model.WebhookDelivery{original},
+	}
+	redelivery, err := db.RedeliverWebhookDelivery(context.Background(), original.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := newTestWorker(db)
+
+	w.tick(context.Background())
+	redelivery.NextAttemptAt = time.Now()
+	w.tick(context.Background())
+
+	if redelivery.Status != model.WebhookDeliveryDelivered || redelivery.Attempts != 2 {
+		t.Errorf("redelivery is %s with %d attempts, want Delivered after 2", redelivery.Status, redelivery.Attempts)
+	}
+	if original.Status != model.WebhookDeliveryFailed || original.Attempts != 3 {
+		t.Errorf("original delivery changed to %s with %d attempts", original.Status, original.Attempts)
+	}
+	if len(*received) != 2 {
+		t.Fatalf("receiver got %d requests, want 2", len(*received))
+	}
+	for _, req := range *received {
+		if got := req.header.Get(DeliveryHeader); got != redelivery.ID {
+			t.Errorf("%s = %q, want the redelivery id %q", DeliveryHeader, got, redelivery.ID)
+		}
+		if string(req.body) != string(original.Payload) {
+			t.Errorf("redelivered body = %s, want the original payload", req.body)
+		}
+	}
+}